@@ -0,0 +1,77 @@
+// forkcfg generates 'struct config' for the C environment from types.KnownForks, and the Go function which
+// fills it according to a Context's ForkSchedule. Usage: forkcfg <output.go> <output.h>
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"os"
+
+	"github.com/smartbch/moeingevm/types"
+)
+
+func goSource() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`// Code generated by cmd/forkcfg DO NOT EDIT.
+
+package ebp
+
+//#include "../evmwrap/host_bridge/bridge.h"
+import "C"
+
+import (
+	"github.com/smartbch/moeingevm/types"
+)
+
+// The fork flags and the parameters passed to the C environment through block_info.cfg
+func newForkConfig(ctx *types.Context) (cfg C.struct_config) {
+`)
+	for _, f := range types.KnownForks {
+		if f.CfgField != "" {
+			fmt.Fprintf(&buf, "\tcfg.%s = C.bool(ctx.IsForkActive(%q))\n", f.CfgField, f.Name)
+		}
+	}
+	buf.WriteString("\tcfg.max_code_size = C.uint32_t(ctx.MaxCodeSize())\n")
+	buf.WriteString("\treturn\n}\n")
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		panic(err)
+	}
+	return src
+}
+
+func cHeader() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`// Code generated by cmd/forkcfg DO NOT EDIT.
+
+#pragma once
+
+#include <stdbool.h>
+#include <stdint.h>
+
+// The forks which are activated at the current block, and the parameters changed by them
+struct config {
+`)
+	for _, f := range types.KnownForks {
+		if f.CfgField != "" {
+			fmt.Fprintf(&buf, "\tbool %s; // %s\n", f.CfgField, f.Name)
+		}
+	}
+	buf.WriteString("\tuint32_t max_code_size; // see ForkSchedule.MaxCodeSize\n")
+	buf.WriteString("};\n")
+	return buf.Bytes()
+}
+
+func main() {
+	if len(os.Args) != 3 {
+		fmt.Println("Usage: forkcfg <output.go> <output.h>")
+		os.Exit(1)
+	}
+	if err := os.WriteFile(os.Args[1], goSource(), 0644); err != nil {
+		panic(err)
+	}
+	if err := os.WriteFile(os.Args[2], cHeader(), 0644); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,149 @@
+package ebp
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/smartbch/moeingevm/types"
+)
+
+const (
+	stakingContractID = 0x2710
+	sep109ContractID  = 0x2713
+)
+
+// The same as is_precompiled in host_context.cpp, ignoring the fork of SEP109
+func isPrecompiledContract(addr common.Address) bool {
+	for _, b := range addr[:12] {
+		if b != 0 {
+			return false
+		}
+	}
+	id := uint64(0)
+	for _, b := range addr[12:] {
+		id = (id << 8) | uint64(b)
+	}
+	return (1 <= id && id <= 9) || (stakingContractID <= id && id <= sep109ContractID)
+}
+
+// Build an EIP-2930 access list from RwLists, which must be recorded with EnableRWList. Like geth's
+// eth_createAccessList, the sender, the recipient and the precompiled contracts are left out unless
+// their storage slots are touched. The accounts which do not exist are not recorded in RwLists, so they
+// are left out, too. The addresses touched as accounts are listed first, then those touched as bytecode,
+// then those touched as storage.
+func (runner *TxRunner) AccessList() gethtypes.AccessList {
+	rw := runner.RwLists
+	seqToAddr := rw.SequenceToAddress()
+	list := make(gethtypes.AccessList, 0, 8)
+	addrIndex := make(map[common.Address]int)
+	slotSet := make(map[common.Address]map[common.Hash]struct{})
+	addAddress := func(addr common.Address) int {
+		if idx, ok := addrIndex[addr]; ok {
+			return idx
+		}
+		addrIndex[addr] = len(list)
+		slotSet[addr] = make(map[common.Hash]struct{})
+		list = append(list, gethtypes.AccessTuple{Address: addr, StorageKeys: []common.Hash{}})
+		return len(list) - 1
+	}
+	to := runner.Tx.To
+	if to == (common.Address{}) {
+		to = runner.CreatedContractAddress
+	}
+	excluded := func(addr common.Address) bool {
+		return addr == runner.Tx.From || addr == to || isPrecompiledContract(addr)
+	}
+	for _, ops := range [][]types.AccountRWOp{rw.AccountRList, rw.AccountWList} {
+		for _, op := range ops {
+			if !excluded(op.Addr) {
+				addAddress(op.Addr)
+			}
+		}
+	}
+	for _, ops := range [][]types.BytecodeRWOp{rw.BytecodeRList, rw.BytecodeWList} {
+		for _, op := range ops {
+			if !excluded(op.Addr) {
+				addAddress(op.Addr)
+			}
+		}
+	}
+	for _, ops := range [][]types.StorageRWOp{rw.StorageRList, rw.StorageWList} {
+		for _, op := range ops {
+			addr, ok := seqToAddr[op.Seq]
+			if !ok || isPrecompiledContract(addr) {
+				continue
+			}
+			idx := addAddress(addr)
+			key := common.BytesToHash([]byte(op.Key))
+			if _, ok := slotSet[addr][key]; !ok {
+				slotSet[addr][key] = struct{}{}
+				list[idx].StorageKeys = append(list[idx].StorageKeys, key)
+			}
+		}
+	}
+	return list
+}
+
+// Run the runner's TX like eth_createAccessList and return the access list it touched, together with the
+// gas used by the TX carrying this list. Like geth, the TX is run again with the list produced by the last
+// run, until the list does not change any more. Each run uses a copy of runner.Ctx, which must be clean and
+// is left unchanged. runner.Tx is not changed either, and the status of the last run is kept in the runner.
+func (exec *txEngine) CreateAccessList(ctx context.Context, currBlock *types.BlockInfo,
+	runner *TxRunner) (gethtypes.AccessList, uint64, error) {
+	runner.ForRpc = true
+	runner.EnableRWList = true
+	rpcCtx, origTx := runner.Ctx, runner.Tx
+	defer func() {
+		runner.Ctx, runner.Tx = rpcCtx, origTx
+	}()
+	tx := *origTx
+	runner.Tx = &tx
+	for {
+		runner.Ctx = rpcCtx.WithRbtCopy()
+		runner.RwLists = &types.ReadWriteLists{}
+		_, err := exec.runnerTable.runTxForRpcWithContext(ctx, currBlock, false, runner)
+		runner.Ctx.Close(false)
+		if err != nil {
+			return nil, 0, err
+		}
+		list := runner.AccessList()
+		if sameAccessList(list, tx.AccessList) {
+			return list, runner.GasUsed, nil
+		}
+		tx.AccessList = list
+	}
+}
+
+// Whether the two access lists contain the same addresses and storage slots, ignoring their order
+func sameAccessList(a, b gethtypes.AccessList) bool {
+	slots := func(list gethtypes.AccessList) map[common.Address]map[common.Hash]struct{} {
+		m := make(map[common.Address]map[common.Hash]struct{}, len(list))
+		for _, tuple := range list {
+			if m[tuple.Address] == nil {
+				m[tuple.Address] = make(map[common.Hash]struct{}, len(tuple.StorageKeys))
+			}
+			for _, key := range tuple.StorageKeys {
+				m[tuple.Address][key] = struct{}{}
+			}
+		}
+		return m
+	}
+	ma, mb := slots(a), slots(b)
+	if len(ma) != len(mb) {
+		return false
+	}
+	for addr, keysA := range ma {
+		keysB, ok := mb[addr]
+		if !ok || len(keysA) != len(keysB) {
+			return false
+		}
+		for key := range keysA {
+			if _, ok := keysB[key]; !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -1,5 +1,7 @@
 package ebp
 
+//go:generate go run ../cmd/forkcfg config_gen.go ../evmwrap/host_bridge/config_gen.h
+
 /*
 #cgo linux LDFLAGS: -l:libevmwrap.a -L../evmwrap/host_bridge -lstdc++ -ldl
 #cgo darwin LDFLAGS: -levmwrap -L../evmwrap/host_bridge -lstdc++ -ldl
@@ -33,7 +35,8 @@ extern void get_value(int handler,
                       size_t* size);
 extern evmc_bytes32 get_block_hash(int handler, uint64_t num);
 extern void collect_result(int handler, struct all_changed* result, struct evmc_result* ret_value);
-extern void call_precompiled_contract (struct evmc_address* contract_addr,
+extern void call_precompiled_contract (int handler,
+                                       struct evmc_address* contract_addr,
                                        void* input_ptr,
                                        int input_size,
                                        uint64_t* gas_left,
@@ -41,6 +44,7 @@ extern void call_precompiled_contract (struct evmc_address* contract_addr,
                                        int* out_of_gas,
                                        struct small_buffer* output_ptr,
                                        int* output_size);
+extern void on_trace_event(int handler, struct trace_event* event);
 
 int64_t zero_depth_call_wrap(evmc_bytes32 gas_price,
                              int64_t gas_limit,
@@ -53,8 +57,17 @@ int64_t zero_depth_call_wrap(evmc_bytes32 gas_price,
                              int collector_handler,
                              bool need_gas_estimation,
                              enum evmc_revision revision,
-		             bridge_query_executor_fn query_executor_fn) {
-       return zero_depth_call(gas_price,
+		             bridge_query_executor_fn query_executor_fn,
+                             volatile int32_t* abort_flag,
+                             int64_t step_budget,
+                             bool tracing,
+                             bool trace_memory,
+                             const struct access_list_item* access_list,
+                             size_t access_list_size) {
+       set_interrupt(abort_flag, step_budget);
+       set_tracer(tracing ? on_trace_event : NULL, trace_memory);
+       set_access_list(access_list, access_list_size);
+       int64_t res = zero_depth_call(gas_price,
                              gas_limit,
                              destination,
                              sender,
@@ -73,6 +86,10 @@ int64_t zero_depth_call_wrap(evmc_bytes32 gas_price,
                              get_block_hash,
                              collect_result,
                              call_precompiled_contract);
+       set_interrupt(NULL, -1);
+       set_tracer(NULL, false);
+       set_access_list(NULL, 0);
+       return res;
 }
 
 bridge_query_executor_fn load_func_from_dl(_GoString_ path, int* status) {
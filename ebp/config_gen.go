@@ -0,0 +1,20 @@
+// Code generated by cmd/forkcfg DO NOT EDIT.
+
+package ebp
+
+//#include "../evmwrap/host_bridge/bridge.h"
+import "C"
+
+import (
+	"github.com/smartbch/moeingevm/types"
+)
+
+// The fork flags and the parameters passed to the C environment through block_info.cfg
+func newForkConfig(ctx *types.Context) (cfg C.struct_config) {
+	cfg.after_xhedge_fork = C.bool(ctx.IsForkActive("xhedge"))
+	cfg.after_symbolsbch_fork = C.bool(ctx.IsForkActive("symbolSbch"))
+	cfg.after_shanghai_fork = C.bool(ctx.IsForkActive("shanghai"))
+	cfg.after_cancun_fork = C.bool(ctx.IsForkActive("cancun"))
+	cfg.max_code_size = C.uint32_t(ctx.MaxCodeSize())
+	return
+}
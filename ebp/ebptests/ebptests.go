@@ -97,13 +97,13 @@ func runTestCase(filename string, theCase *tc.TestCase, printLog bool) {
 	trunk = root.GetTrunkStore(1000).(*store.TrunkStore)
 	var chainId big.Int
 	chainId.SetBytes(currBlock.ChainId[:])
-	txEngine := ebp.NewEbpTxExec(10, 100, 32, 100, &tc.DumbSigner{}, log.NewNopLogger())
+	txEngine := ebp.NewEbpTxExec(10, 100, 32, 100, &tc.DumbSigner{}, nil, log.NewNopLogger())
 	ctx := types.NewContext(nil, nil)
 	rbt = rabbit.NewRabbitStore(trunk)
 	ctx = ctx.WithRbt(&rbt)
 	txEngine.SetContext(ctx)
 	txEngine.CollectTx(currTx)
-	txEngine.Prepare(0, 0, ebp.DefaultTxGasLimit)
+	txEngine.Prepare(0, 0, ebp.DefaultTxGasLimit, nil)
 	txList := txEngine.CommittedTxs()
 	fmt.Printf("after Prepare txList len %d\n", len(txList))
 	txEngine.Execute(&currBlock.BlockInfo)
@@ -1,15 +1,17 @@
 package ebp
 
 import (
+	"context"
 	"encoding/binary"
 	"errors"
+	"math"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	gethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/holiman/uint256"
-	"github.com/seehuhn/mt19937"
 	"github.com/tendermint/tendermint/libs/log"
 
 	dt "github.com/smartbch/moeingads/datatree"
@@ -27,9 +29,28 @@ const (
 )
 
 var (
-	Sep206Address  = common.HexToAddress("0x0000000000000000000000000000000000002711")
-	BlockedAddress = common.HexToAddress("0x8c4F85ec71C966e45A6F4291f5271f8114a7Ba15")
+	Sep206Address = common.HexToAddress("0x0000000000000000000000000000000000002711")
+	// TXs from this address were rejected by a hard-coded check before the blocklist was stored in world state
+	LegacyBlockedSender = common.HexToAddress("0x8c4F85ec71C966e45A6F4291f5271f8114a7Ba15")
 )
+
+// Insert LegacyBlockedSender into the blocklist with activation height 0, unless it is already there.
+// Without the migration, LegacyBlockedSender is still blocked by isBlockedSender.
+func MigrateLegacyBlockedSender(ctx *types.Context) {
+	if _, found := ctx.GetBlockedSender(LegacyBlockedSender); !found {
+		ctx.SetBlockedSender(LegacyBlockedSender, 0)
+	}
+}
+
+// The blocklist in world state decides whether 'addr' is blocked. LegacyBlockedSender falls back to the
+// hard-coded check if the blocklist has no entry for it, so a chain which never migrates keeps blocking it.
+func isBlockedSender(ctx *types.Context, addr common.Address) bool {
+	if _, found := ctx.GetBlockedSender(addr); found {
+		return ctx.IsBlockedSender(addr)
+	}
+	return addr == LegacyBlockedSender
+}
+
 var _ TxExecutor = (*txEngine)(nil)
 
 type TxRange struct {
@@ -95,17 +116,40 @@ type txEngine struct {
 	// Used to check signatures
 	signer       gethtypes.Signer
 	currentBlock *types.BlockInfo
+	// Decides the order of senders in standby queue
+	orderer TxOrderer
+	// The parameters used by the last 'Prepare'
+	minGasPrice   uint64
+	maxTxGasLimit uint64
+	// The runners and system contracts owned by this engine, 'runners' is the same as 'runnerTable.runners'
+	runnerTable *runnerTable
+	runners     []*TxRunner
 
 	rwListMap        map[common.Hash]rwList
 	checkRWInLoading bool
+	// Use the DAG scheduler in scheduler.go instead of the round-based one
+	useDAGScheduler bool
+	// Not nil if the exact-key mode in exactkey.go is enabled
+	exactKeyConfig *ExactKeyConflictConfig
 
 	cumulativeGasUsed   uint64
 	cumulativeFeeRefund *uint256.Int
-	cumulativeGasFee    *uint256.Int
+	cumulativeGasFee    *uint256.Int // excluding cumulativeBurntFee
+	cumulativeBurntFee  *uint256.Int
+
+	// The gas consumed by the runners which are written back, compared against the block's gas limit
+	blockGasUsed uint64
+	// Once it is set, no more runners can be committed in the current block
+	gasLimitReached bool
 
 	aotDir            string
 	aotReloadInterval int64
 
+	// The details of the last 'Execute'
+	report *ExecutionReport
+	// Not nil if the committed TXs are profiled, see profiler.go
+	gasProfile *GasProfile
+
 	logger log.Logger
 
 	// for ut
@@ -118,9 +162,9 @@ func (exec *txEngine) Context() *types.Context {
 
 // Generated by parallelReadAccounts and insertToStandbyTxQ will store its tx into world state.
 type preparedInfo struct {
-	tx       *types.TxToRun
-	txBytes  []byte
-	errorStr string
+	tx        *types.TxToRun
+	txBytes   []byte
+	rejection *types.TxRejection // not nil if the TX is rejected
 }
 
 // Generated by parallelReadAccounts and Prepare will use them for some validations.
@@ -211,16 +255,25 @@ func GetEmptyFrontier() Frontier {
 	}
 }
 
-func NewEbpTxExec(exeRoundCount, runnerNumber, parallelNum, defaultTxListCap int, s gethtypes.Signer, logger log.Logger) *txEngine {
-	Runners = make([]*TxRunner, runnerNumber)
+// If 'orderer' is nil, RandomTxOrderer is used
+func NewEbpTxExec(exeRoundCount, runnerNumber, parallelNum, defaultTxListCap int, s gethtypes.Signer,
+	orderer TxOrderer, logger log.Logger) *txEngine {
+	if orderer == nil {
+		orderer = RandomTxOrderer{}
+	}
+	table := newRunnerTable(runnerNumber)
 	return &txEngine{
-		roundNum:     exeRoundCount,
-		runnerNumber: runnerNumber,
-		parallelNum:  parallelNum,
-		txList:       make([]*gethtypes.Transaction, 0, defaultTxListCap),
-		committedTxs: make([]*types.Transaction, 0, defaultTxListCap),
-		signer:       s,
-		logger:       logger,
+		runnerTable:   table,
+		runners:       table.runners,
+		roundNum:      exeRoundCount,
+		runnerNumber:  runnerNumber,
+		parallelNum:   parallelNum,
+		txList:        make([]*gethtypes.Transaction, 0, defaultTxListCap),
+		committedTxs:  make([]*types.Transaction, 0, defaultTxListCap),
+		signer:        s,
+		orderer:       orderer,
+		maxTxGasLimit: DefaultTxGasLimit,
+		logger:        logger,
 	}
 }
 
@@ -228,6 +281,41 @@ func (exec *txEngine) SetCheckRWInLoading(b bool) {
 	exec.checkRWInLoading = b
 }
 
+func (exec *txEngine) SetDAGScheduling(b bool) {
+	exec.useDAGScheduler = b
+}
+
+// Register a system contract which can only be called by the TXs executed by this engine
+func (exec *txEngine) RegisterPredefinedContract(ctx *types.Context, address common.Address, executor types.SystemContractExecutor) {
+	exec.runnerTable.registerPredefinedContract(ctx, address, executor)
+}
+
+// Run a TX for Web3 RPC (call and estimateGas) with this engine's system contracts
+func (exec *txEngine) RunTxForRpc(currBlock *types.BlockInfo, estimateGas bool, runner *TxRunner) int64 {
+	return exec.runnerTable.runTxForRpc(currBlock, estimateGas, runner)
+}
+
+// Like RunTxForRpc, but it returns ErrRpcQueueFull at once if too many calls are waiting for idle runners.
+// The execution is aborted with ctx.Err() once 'ctx' is done, or with ErrStepBudgetExceeded once the step
+// budget is exhausted. The aborted runner's status is EVMC_INTERNAL_ERROR. A call which finishes before
+// noticing that 'ctx' is done returns its result as usual.
+func (exec *txEngine) RunTxForRpcWithContext(ctx context.Context, currBlock *types.BlockInfo, estimateGas bool,
+	runner *TxRunner) (int64, error) {
+	return exec.runnerTable.runTxForRpcWithContext(ctx, currBlock, estimateGas, runner)
+}
+
+// Set how many calls of RunTxForRpcWithContext can wait for idle runners, and how many basic blocks of
+// EVM bytecode each of them can execute (negative means no limit). It is safe to call it concurrently.
+func (exec *txEngine) SetRpcLimits(queueLimit int, stepBudget int64) {
+	atomic.StoreInt32(&exec.runnerTable.rpcQueueLimit, int32(queueLimit))
+	atomic.StoreInt64(&exec.runnerTable.rpcStepBudget, stepBudget)
+}
+
+// Release the handlers used by this engine. The engine cannot be used any more after Close
+func (exec *txEngine) Close() {
+	exec.runnerTable.release()
+}
+
 func (exec *txEngine) SetAotParam(aotDir string, aotReloadInterval int64) {
 	exec.aotDir = aotDir
 	exec.aotReloadInterval = aotReloadInterval
@@ -238,14 +326,16 @@ func (exec *txEngine) SetContext(ctx *types.Context) {
 	exec.cleanCtx = ctx
 }
 
-// Check transactions' signatures and insert the valid ones into standby queue
-func (exec *txEngine) Prepare(reorderSeed int64, minGasPrice, maxTxGasLimit uint64) Frontier {
+// Check transactions' signatures and insert the valid ones into standby queue. After the London fork, the
+// dynamic-fee TXs are priced with 'baseFee', which must be the base fee of the pending block (nil means zero).
+func (exec *txEngine) Prepare(reorderSeed int64, minGasPrice, maxTxGasLimit uint64, baseFee *uint256.Int) Frontier {
+	exec.minGasPrice, exec.maxTxGasLimit = minGasPrice, maxTxGasLimit
 	exec.cleanCtx.Rbt.GetBaseStore().PrepareForUpdate(types.StandbyTxQueueKey[:])
 	if len(exec.txList) == 0 {
 		exec.cleanCtx.Close(false)
 		return GetEmptyFrontier()
 	}
-	infoList, ctxAA := exec.parallelReadAccounts(minGasPrice, maxTxGasLimit)
+	infoList, ctxAA := exec.parallelReadAccounts(minGasPrice, maxTxGasLimit, exec.londonBaseFee(baseFee))
 	addr2idx := make(map[common.Address]int, len(exec.txList)) // map address to ctxAA's index
 	for idx, entry := range ctxAA {
 		for _, addr := range entry.accounts {
@@ -255,7 +345,7 @@ func (exec *txEngine) Prepare(reorderSeed int64, minGasPrice, maxTxGasLimit uint
 			}
 		}
 	}
-	reorderedList, addr2Infos := reorderInfoList(infoList, reorderSeed)
+	reorderedList, addr2Infos := reorderInfoList(infoList, reorderSeed, exec.orderer)
 	ctx := exec.cleanCtx.WithRbtCopy()
 	startEndBz := ctx.Rbt.GetBaseStore().Get(types.StandbyTxQueueKey[:])
 	queueEnd := uint64(0)
@@ -278,14 +368,15 @@ func (exec *txEngine) Prepare(reorderSeed int64, minGasPrice, maxTxGasLimit uint
 				continue
 			}
 			for _, info := range addr2Infos[addr] {
-				if len(info.errorStr) != 0 {
+				if info.rejection != nil {
 					continue //skip it if already found error
 				}
 				sender := info.tx.From
 				if entry.addr2nonce[sender] != info.tx.Nonce {
 					//skip it if nonce is wrong
 					exec.logger.Debug("prepare::incorrect nonce", "txHash", info.tx.HashID.String())
-					info.errorStr = "incorrect nonce"
+					info.rejection = &types.TxRejection{Code: types.REJECTED_INCORRECT_NONCE,
+						ExpectedNonce: entry.addr2nonce[sender], ActualNonce: info.tx.Nonce}
 					continue
 				}
 				entry.addr2nonce[sender]++
@@ -324,17 +415,21 @@ func (exec *txEngine) deductGasFeeAndUpdateFrontier(sender common.Address, info
 		gasPrice = uint256.NewInt(MaxGasPrice)
 	}
 	gasFee.Mul(gasFee, gasPrice)
-	if info.tx.From == BlockedAddress {
+	if isBlockedSender(entry.ctx, info.tx.From) {
 		exec.logger.Debug("Blocked Account", "txHash", info.tx.HashID.String())
 		entry.addr2Balance[sender] = uint256.NewInt(0)
-		info.errorStr = "Blocked Account"
+		info.rejection = &types.TxRejection{Code: types.REJECTED_BLOCKED_SENDER}
 		return errors.New("Blocked Account")
 	}
 	err := SubSenderAccBalance(entry.ctx, sender, gasFee)
 	if err != nil {
 		exec.logger.Debug("prepare::deduct gas fee failed", "txHash", info.tx.HashID.String())
 		entry.addr2Balance[sender] = uint256.NewInt(0)
-		info.errorStr = "not enough balance to pay gasfee"
+		info.rejection = &types.TxRejection{Code: types.REJECTED_INSUFFICIENT_BALANCE}
+		if acc := entry.ctx.GetAccount(sender); acc != nil {
+			gasFee.Sub(gasFee, acc.Balance())
+		}
+		info.rejection.Shortfall = gasFee.Bytes32()
 		return err
 	} else {
 		if info.tx.To == Sep206Address {
@@ -370,13 +465,21 @@ func (exec *txEngine) getCurrHeight() uint64 {
 }
 
 // Read accounts' information in parallel, while checking accounts' existence and signatures' validity
-func (exec *txEngine) parallelReadAccounts(minGasPrice, maxTxGasLimit uint64) (infoList []*preparedInfo, ctxAA []*ctxAndAccounts) {
+func (exec *txEngine) parallelReadAccounts(minGasPrice, maxTxGasLimit uint64, baseFee *uint256.Int) (infoList []*preparedInfo, ctxAA []*ctxAndAccounts) {
 	//for each tx, we fetch some info for it
 	infoList = make([]*preparedInfo, len(exec.txList))
 	//the ctx and accounts that a worker works at
 	ctxAA = make([]*ctxAndAccounts, exec.parallelNum)
 	sharedIdx := int64(-1)
 	estimatedSize := len(exec.txList)/exec.parallelNum + 1
+	// after the London fork, the gas price must cover the base fee, too
+	minPrice := minGasPrice
+	if !baseFee.IsUint64() {
+		minPrice = math.MaxUint64
+	} else if baseFee.Uint64() > minPrice {
+		minPrice = baseFee.Uint64()
+	}
+	baseFeeBig := baseFee.ToBig()
 	dt.ParallelRun(exec.parallelNum, func(workerId int) {
 		ctxAA[workerId] = &ctxAndAccounts{
 			ctx:          exec.cleanCtx.WithRbtCopy(),
@@ -400,21 +503,35 @@ func (exec *txEngine) parallelReadAccounts(minGasPrice, maxTxGasLimit uint64) (i
 			txToRun.FromGethTx(tx, sender, exec.getCurrHeight())
 			infoList[myIdx].tx = txToRun
 			if err != nil {
-				infoList[myIdx].errorStr = "invalid signature"
+				infoList[myIdx].rejection = &types.TxRejection{Code: types.REJECTED_INVALID_SIGNATURE}
+				continue
+			}
+			if !txTypeAllowed(ctxAA[workerId].ctx, tx.Type()) {
+				infoList[myIdx].rejection = &types.TxRejection{Code: types.REJECTED_TX_TYPE_NOT_ALLOWED}
 				continue
 			}
-			if !tx.GasPrice().IsInt64() || tx.GasPrice().Int64() < int64(minGasPrice) {
-				infoList[myIdx].errorStr = "invalid gas price"
+			if tx.GasTipCapIntCmp(tx.GasFeeCap()) > 0 {
+				infoList[myIdx].rejection = &types.TxRejection{Code: types.REJECTED_TIP_ABOVE_FEE_CAP}
+				continue
+			}
+			gasPrice := types.EffectiveGasPrice(tx, baseFeeBig)
+			copy(txToRun.GasPrice[:], utils.BigIntToSlice32(gasPrice))
+			if !gasPrice.IsInt64() {
+				infoList[myIdx].rejection = &types.TxRejection{Code: types.REJECTED_GAS_PRICE_TOO_HIGH}
+				continue
+			}
+			if uint64(gasPrice.Int64()) < minPrice {
+				infoList[myIdx].rejection = &types.TxRejection{Code: types.REJECTED_GAS_PRICE_TOO_LOW, MinGasPrice: minPrice}
 				continue
 			}
 			if tx.Gas() > maxTxGasLimit {
-				infoList[myIdx].errorStr = "invalid gas limit"
+				infoList[myIdx].rejection = &types.TxRejection{Code: types.REJECTED_GAS_LIMIT_TOO_HIGH, MaxGasLimit: maxTxGasLimit}
 				continue
 			}
 			// access disk to fetch the account's detail
 			acc := ctxAA[workerId].ctx.GetAccount(sender)
 			if acc == nil {
-				infoList[myIdx].errorStr = "non-existent account"
+				infoList[myIdx].rejection = &types.TxRejection{Code: types.REJECTED_ACCOUNT_NOT_EXIST}
 				continue
 			}
 			if _, ok := ctxAA[workerId].addr2nonce[sender]; !ok {
@@ -427,7 +544,21 @@ func (exec *txEngine) parallelReadAccounts(minGasPrice, maxTxGasLimit uint64) (i
 	return
 }
 
-func reorderInfoList(infoList []*preparedInfo, reorderSeed int64) (out []*preparedInfo, addr2Infos map[common.Address][]*preparedInfo) {
+// The typed TXs are only allowed after the forks introducing them
+func txTypeAllowed(ctx *types.Context, txType uint8) bool {
+	switch txType {
+	case gethtypes.LegacyTxType:
+		return true
+	case gethtypes.AccessListTxType:
+		return ctx.IsForkActive(types.BerlinFork)
+	case gethtypes.DynamicFeeTxType:
+		return ctx.IsForkActive(types.LondonFork)
+	}
+	return false
+}
+
+// Group the TXs by their senders and let 'orderer' decide the order of the groups
+func reorderInfoList(infoList []*preparedInfo, reorderSeed int64, orderer TxOrderer) (out []*preparedInfo, addr2Infos map[common.Address][]*preparedInfo) {
 	out = make([]*preparedInfo, 0, len(infoList))
 	addr2Infos = make(map[common.Address][]*preparedInfo, len(infoList))
 	addrList := make([]common.Address, 0, len(infoList))
@@ -439,15 +570,16 @@ func reorderInfoList(infoList []*preparedInfo, reorderSeed int64) (out []*prepar
 			addrList = append(addrList, info.tx.From)
 		}
 	}
-	rand := mt19937.New()
-	rand.Seed(reorderSeed)
-	for i := 0; i < len(addrList); i++ { // shuffle the addresses
-		r0 := int(rand.Int63()) % len(addrList)
-		r1 := int(rand.Int63()) % len(addrList)
-		addrList[r0], addrList[r1] = addrList[r1], addrList[r0]
+	groups := make([][]*types.TxToRun, len(addrList))
+	for i, addr := range addrList {
+		groups[i] = make([]*types.TxToRun, len(addr2Infos[addr]))
+		for j, info := range addr2Infos[addr] {
+			groups[i][j] = info.tx
+		}
 	}
-	for _, addr := range addrList {
-		out = append(out, addr2Infos[addr]...)
+	orderer.OrderSenders(groups, reorderSeed)
+	for _, group := range groups { // only the order of groups is taken, so same-sender TXs stay back-to-back
+		out = append(out, addr2Infos[group[0].From]...)
 	}
 	return
 }
@@ -459,7 +591,7 @@ func (exec *txEngine) insertToStandbyTxQ(trunk storetypes.BaseStoreI, infoList [
 			// six kinds of errors: invalid signature; incorrect nonce;
 			// no such account; balance not enough; gas limit too high; gas price too low;
 			// if the proposor is honest, there should be no these kinds of errors.
-			if len(info.errorStr) != 0 {
+			if info.rejection != nil {
 				exec.recordInvalidTx(info)
 				continue
 			}
@@ -487,7 +619,8 @@ func (exec *txEngine) recordInvalidTx(info *preparedInfo) {
 		CumulativeGasUsed: exec.cumulativeGasUsed,
 		GasUsed:           0,
 		Status:            gethtypes.ReceiptStatusFailed,
-		StatusStr:         info.errorStr,
+		StatusStr:         info.rejection.String(),
+		Rejection:         info.rejection,
 	}
 	if exec.currentBlock != nil {
 		tx.BlockHash = exec.currentBlock.Hash
@@ -501,9 +634,16 @@ func (exec *txEngine) Execute(currBlock *types.BlockInfo) {
 	exec.cumulativeGasUsed = 0
 	exec.cumulativeFeeRefund = uint256.NewInt(0)
 	exec.cumulativeGasFee = uint256.NewInt(0)
+	exec.cumulativeBurntFee = uint256.NewInt(0)
 	exec.currentBlock = currBlock
 	exec.rwListMap = make(map[common.Hash]rwList, 1024)
+	startTime := time.Now()
+	exec.report = &ExecutionReport{Height: currBlock.Number}
+	defer func() {
+		exec.report.TotalTime = time.Since(startTime)
+	}()
 	startKey, endKey := exec.getStandbyQueueRange()
+	exec.report.StandbyQLenBefore = int(endKey - startKey)
 	if startKey == endKey {
 		return
 	}
@@ -512,6 +652,63 @@ func (exec *txEngine) Execute(currBlock *types.BlockInfo) {
 		end:   endKey,
 	}
 	exec.txExecutedCount = 0
+	exec.blockGasUsed = 0
+	exec.gasLimitReached = false
+	var committableRunnerList []*TxRunner
+	if exec.useDAGScheduler {
+		committableRunnerList = exec.executeWithDAG(txRange, exec.currentBlock)
+	} else {
+		committableRunnerList = exec.executeInRounds(txRange)
+	}
+	exec.setStandbyQueueRange(txRange.start, txRange.end)
+	exec.report.StandbyQLenAfter = int(txRange.end - txRange.start)
+	collectStart := time.Now()
+	exec.collectCommittableTxs(committableRunnerList)
+	exec.report.CollectTime = time.Since(collectStart)
+	exec.burnGasFee()
+	exec.reloadQueryExecutorFn()
+}
+
+// The base fee of the current block, which is ignored before the London fork
+func (exec *txEngine) currentBaseFee() *uint256.Int {
+	if exec.currentBlock == nil {
+		return uint256.NewInt(0)
+	}
+	return exec.londonBaseFee(uint256.NewInt(0).SetBytes32(exec.currentBlock.BaseFee[:]))
+}
+
+// Return a copy of 'baseFee', or zero if it is nil or the London fork is not activated
+func (exec *txEngine) londonBaseFee(baseFee *uint256.Int) *uint256.Int {
+	if baseFee == nil || !exec.cleanCtx.IsForkActive(types.LondonFork) {
+		return uint256.NewInt(0)
+	}
+	return baseFee.Clone()
+}
+
+// Add the gas fee of a TX to cumulativeGasFee, except the part burnt according to EIP-1559, which is added to
+// cumulativeBurntFee
+func (exec *txEngine) collectGasFee(runner *TxRunner) {
+	gasFee := runner.GetGasFee()
+	burnt := runner.GetBurntGasFee(exec.currentBaseFee())
+	exec.cumulativeGasFee.Add(exec.cumulativeGasFee, gasFee.Sub(gasFee, burnt))
+	exec.cumulativeBurntFee.Add(exec.cumulativeBurntFee, burnt)
+}
+
+// Move the burnt gas fees from the system account, which received all the gas fees in Prepare, to the
+// black hole account
+func (exec *txEngine) burnGasFee() {
+	if exec.cumulativeBurntFee.IsZero() {
+		return
+	}
+	ctx := exec.cleanCtx.WithRbtCopy()
+	if err := TransferFromSystemAccToBlackHoleAcc(ctx, exec.cumulativeBurntFee); err != nil {
+		panic(err) // the system account must be able to pay, since the burnt fees are a part of its income
+	}
+	ctx.Close(true)
+}
+
+// Execute TXs in the standby queue round by round and return all the committable runners
+func (exec *txEngine) executeInRounds(txRange *TxRange) []*TxRunner {
 	committableRunnerList := make([]*TxRunner, 0, 4096)
 	// Repeat exec.roundNum round for execute txs in standby q. At the end of each round
 	// modifications made by TXs are written to world state. So TXs in later rounds can
@@ -526,16 +723,17 @@ func (exec *txEngine) Execute(currBlock *types.BlockInfo) {
 			break
 		}
 		for i := 0; i < numTx; i++ {
-			if Runners[i] == nil {
+			if exec.runners[i] == nil {
 				continue // the TX is not committable and needs re-execution
 			}
-			committableRunnerList = append(committableRunnerList, Runners[i])
-			Runners[i] = nil
+			committableRunnerList = append(committableRunnerList, exec.runners[i])
+			exec.runners[i] = nil
+		}
+		if exec.gasLimitReached {
+			break
 		}
 	}
-	exec.setStandbyQueueRange(txRange.start, txRange.end)
-	exec.collectCommittableTxs(committableRunnerList)
-	exec.reloadQueryExecutorFn()
+	return committableRunnerList
 }
 
 // Get the start and end position of standby queue
@@ -562,17 +760,26 @@ func (exec *txEngine) setStandbyQueueRange(start, end uint64) {
 
 // Execute 'runnerNumber' transactions in parallel and commit the ones without any interdependency
 func (exec *txEngine) executeOneRound(txRange *TxRange, currBlock *types.BlockInfo) int {
-	txBundle, ignoreList := exec.loadStandbyTxs(txRange)
+	round := exec.report.newRound()
+	startTime := time.Now()
+	txBundle, ignoreList, ignoredOffsets := exec.loadStandbyTxs(txRange)
+	round.LoadTime = time.Since(startTime)
 	if exec.checkRWInLoading && len(txBundle) == 0 {
 		return 0
 	}
+	startTime = time.Now()
 	kvCount := exec.runTxInParallel(txRange, txBundle, len(ignoreList), currBlock)
-	exec.checkTxDepsAndUptStandbyQ(txRange, txBundle, ignoreList, int(kvCount))
+	round.RunTime = time.Since(startTime)
+	startTime = time.Now()
+	exec.checkTxDepsAndUptStandbyQ(txRange, txBundle, ignoreList, ignoredOffsets, int(kvCount))
+	round.CommitTime = time.Since(startTime)
+	round.recordIgnored(ignoreList)
 	return len(txBundle)
 }
 
-// Load at most 'exec.runnerNumber' transactions from standby queue
-func (exec *txEngine) loadStandbyTxs(txRange *TxRange) (txBundle, ignoreList []types.TxToRun) {
+// Load at most 'exec.runnerNumber' transactions from standby queue. The TXs in 'ignoreList' conflict with
+// the ones loaded before them, and 'ignoredOffsets' records their offsets from 'txRange.start' in the queue.
+func (exec *txEngine) loadStandbyTxs(txRange *TxRange) (txBundle, ignoreList []types.TxToRun, ignoredOffsets []int) {
 	touchedSet := make(map[uint64]struct{}, 4096)
 	ctx := exec.cleanCtx.WithRbtCopy()
 	txBundle = make([]types.TxToRun, 0, exec.runnerNumber)
@@ -586,6 +793,7 @@ func (exec *txEngine) loadStandbyTxs(txRange *TxRange) (txBundle, ignoreList []t
 		hasConflicts := exec.checkRWInLoading && isRecorded && rwList.conflictsWith(touchedSet)
 		if hasConflicts {
 			ignoreList = append(ignoreList, txToRun)
+			ignoredOffsets = append(ignoredOffsets, int(i-txRange.start))
 		} else {
 			rwList.updateTouchedSet(touchedSet)
 			txBundle = append(txBundle, txToRun)
@@ -595,7 +803,7 @@ func (exec *txEngine) loadStandbyTxs(txRange *TxRange) (txBundle, ignoreList []t
 	return
 }
 
-// Assign the transactions to global 'Runners' and run them in parallel.
+// Assign the transactions to 'exec.runners' and run them in parallel.
 // Record the count of touched KV pairs and return it as a hint for checkTxDepsAndUptStandbyQ
 func (exec *txEngine) runTxInParallel(txRange *TxRange, txBundle []types.TxToRun, ignoreLen int, currBlock *types.BlockInfo) (kvCount int64) {
 	sharedIdx := int64(-1)
@@ -613,14 +821,14 @@ func (exec *txEngine) runTxInParallel(txRange *TxRange, txBundle []types.TxToRun
 			if myIdx >= int64(len(txBundle)) {
 				continue
 			}
-			Runners[myIdx] = NewTxRunner(exec.cleanCtx.WithRbtCopy(), &txBundle[myIdx])
+			exec.runners[myIdx] = exec.newTxRunner(&txBundle[myIdx])
 			if myIdx > 0 && txBundle[myIdx-1].From == txBundle[myIdx].From {
 				// In reorderInfoList, we placed the tx with same 'From' back-to-back
 				// same from-address as previous transaction, cannot run in same round
-				Runners[myIdx].Status = types.TX_NONCE_TOO_LARGE
+				exec.runners[myIdx].Status = types.TX_NONCE_TOO_LARGE
 			} else {
-				runTx(int(myIdx), currBlock)
-				atomic.AddInt64(&kvCount, int64(Runners[myIdx].Ctx.Rbt.CachedEntryCount()))
+				exec.runnerTable.runTx(int(myIdx), currBlock)
+				atomic.AddInt64(&kvCount, int64(exec.runners[myIdx].Ctx.Rbt.CachedEntryCount()))
 			}
 		}
 	})
@@ -634,8 +842,111 @@ type indexAndBool struct {
 
 // Check interdependency of TXs using 'touchedSet'. The ones with dependency with former committed TXs cannot
 // be committed and should be inserted back into the standby queue.
-func (exec *txEngine) checkTxDepsAndUptStandbyQ(txRange *TxRange, txBundle, ignoreList []types.TxToRun, kvCount int) {
+func (exec *txEngine) checkTxDepsAndUptStandbyQ(txRange *TxRange, txBundle, ignoreList []types.TxToRun,
+	ignoredOffsets []int, kvCount int) {
+	exec.commitNonConflictingRunners(len(txBundle), kvCount, exec.checkRWInLoading)
+
+	round := exec.report.currentRound()
+	trunk := exec.cleanCtx.Rbt.GetBaseStore()
+	trunk.Update(func(store storetypes.SetDeleter) {
+		failedTxs := make([]types.TxToRun, 0, len(txBundle)+len(ignoreList))
+		failed := make([]bool, len(txBundle))
+		for idx, tx := range txBundle {
+			round.recordRunner(exec.runners[idx])
+			status := exec.runners[idx].Status
+			k := types.GetStandbyTxKey(txRange.start)
+			txRange.start++
+			store.Delete(k)
+			if status == types.FAILED_TO_COMMIT || status == types.TX_NONCE_TOO_LARGE {
+				failedTxs = append(failedTxs, tx)
+				failed[idx] = true
+				exec.runners[idx] = nil
+			} else if status == types.ACCOUNT_NOT_EXIST || status == types.TX_NONCE_TOO_SMALL {
+				//collect invalid tx`s all gas
+				exec.cumulativeGasUsed += exec.runners[idx].Tx.Gas
+				exec.collectGasFee(exec.runners[idx])
+				exec.runners[idx] = nil
+			}
+		}
+		for _, tx := range ignoreList {
+			k := types.GetStandbyTxKey(txRange.start)
+			store.Delete(k)
+			txRange.start++
+			failedTxs = append(failedTxs, tx)
+		}
+		if exec.gasLimitReached { // they are put back at the head, so they must keep their order in the queue
+			failedTxs = mergeInQueueOrder(txBundle, failed, ignoreList, ignoredOffsets)
+		}
+		exec.insertBackToStandbyQ(store, txRange, failedTxs)
+	})
+}
+
+// Merge the failed TXs in 'txBundle' with the ones in 'ignoreList', in the order they were loaded from the
+// standby queue. The TXs not in 'ignoreList' were loaded into 'txBundle' one by one.
+func mergeInQueueOrder(txBundle []types.TxToRun, failed []bool, ignoreList []types.TxToRun, ignoredOffsets []int) []types.TxToRun {
+	txList := make([]types.TxToRun, 0, len(txBundle)+len(ignoreList))
+	bundleIdx, ignoredIdx := 0, 0
+	for offset := 0; offset < len(txBundle)+len(ignoreList); offset++ {
+		if ignoredIdx < len(ignoreList) && ignoredOffsets[ignoredIdx] == offset {
+			txList = append(txList, ignoreList[ignoredIdx])
+			ignoredIdx++
+			continue
+		}
+		if failed[bundleIdx] {
+			txList = append(txList, txBundle[bundleIdx])
+		}
+		bundleIdx++
+	}
+	return txList
+}
+
+// Insert the TXs which were removed from the standby queue but not committed back into it. Normally they are
+// appended at the end for re-execution in later rounds. Once the block gas limit is reached, they are put back
+// at the head, such that they stay before the remaining TXs and will be executed first in the next block.
+func (exec *txEngine) insertBackToStandbyQ(store storetypes.SetDeleter, txRange *TxRange, txList []types.TxToRun) {
+	if exec.gasLimitReached {
+		txRange.start -= uint64(len(txList)) // these positions were just freed
+		for i, tx := range txList {
+			store.Set(types.GetStandbyTxKey(txRange.start+uint64(i)), tx.ToBytes())
+		}
+		return
+	}
+	for _, tx := range txList {
+		store.Set(types.GetStandbyTxKey(txRange.end), tx.ToBytes())
+		txRange.end++
+	}
+}
+
+// Check whether the runner's gas still fits in the block gas limit and consume it if so. Once a runner is
+// refused, all the following runners are refused too. The runners are checked in a deterministic order, so
+// the committed TXs do not depend on 'parallelNum'. A non-positive gas limit means no limit.
+func (exec *txEngine) consumeBlockGas(runner *TxRunner) bool {
+	if exec.gasLimitReached {
+		return false
+	}
+	gas := runner.GasUsed
+	if runner.Status == types.ACCOUNT_NOT_EXIST || runner.Status == types.TX_NONCE_TOO_SMALL {
+		gas = runner.Tx.Gas // invalid TXs are charged with all their gas
+	}
+	gasLimit := exec.currentBlock.GasLimit
+	if gasLimit > 0 && exec.blockGasUsed+gas > uint64(gasLimit) {
+		exec.gasLimitReached = true
+		return false
+	}
+	exec.blockGasUsed += gas
+	return true
+}
+
+// Check the first 'count' runners in order. A runner is committable only if it does not touch the KV pairs
+// written by former committable runners. The rabbit stores of committable runners are written back and the
+// others are marked as FAILED_TO_COMMIT. If 'recordRWList' is true, the touched KV pairs are kept in rwListMap.
+func (exec *txEngine) commitNonConflictingRunners(count, kvCount int, recordRWList bool) {
 	touchedSet := make(map[uint64]struct{}, kvCount)
+	round := exec.report.currentRound()
+	var exact *exactKeyChecker
+	if exec.exactKeyConfig != nil {
+		exact = exec.newExactKeyChecker()
+	}
 	var wg sync.WaitGroup
 	idxChan := make(chan indexAndBool, 10)
 	wg.Add(1)
@@ -645,29 +956,50 @@ func (exec *txEngine) checkTxDepsAndUptStandbyQ(txRange *TxRange, txBundle, igno
 			if idxAndBool.idx < 0 {
 				break
 			}
-			Runners[idxAndBool.idx].Ctx.Rbt.CloseAndWriteBack(idxAndBool.canCommit)
+			exec.runners[idxAndBool.idx].Ctx.Rbt.CloseAndWriteBack(idxAndBool.canCommit)
 		}
 		wg.Done()
 	}()
 	rwList := newRWList()
-	for idx := range txBundle {
+	for idx := 0; idx < count; idx++ {
 		canCommit := true
-		Runners[idx].Ctx.Rbt.ScanAllShortKeys(func(key [rabbit.KeySize]byte, dirty bool) (stop bool) {
+		runner := exec.runners[idx]
+		var sysDelta *uint256.Int
+		var isAdd, mergeSys bool
+		if exact != nil {
+			sysDelta, isAdd, mergeSys = exact.systemBalanceDelta(runner)
+		}
+		runner.Ctx.Rbt.ScanAllShortKeys(func(key [rabbit.KeySize]byte, dirty bool) (stop bool) {
 			k := binary.LittleEndian.Uint64(key[:])
 			rwList.add(k, dirty)
-			if canCommit {
+			if canCommit && (exact == nil || exact.checksShortKey(runner, k, dirty, mergeSys)) {
 				if _, ok := touchedSet[k]; ok {
 					canCommit = false // cannot commit if conflicts with touched KV set
-					Runners[idx].Status = types.FAILED_TO_COMMIT
+					runner.Status = types.FAILED_TO_COMMIT
+					round.Conflicts = append(round.Conflicts, TxConflict{TxHash: runner.Tx.HashID, ShortKey: key})
 				}
 			}
 			return false
 		})
+		if canCommit && exact != nil {
+			if key := exact.conflictingKey(runner, mergeSys); key != nil {
+				canCommit = false
+				runner.Status = types.FAILED_TO_COMMIT
+				round.Conflicts = append(round.Conflicts, TxConflict{TxHash: runner.Tx.HashID, Key: key})
+			}
+		}
+		if canCommit && runner.Status != types.TX_NONCE_TOO_LARGE && !exec.consumeBlockGas(runner) {
+			canCommit = false
+			runner.Status = types.FAILED_TO_COMMIT
+		}
 		if canCommit { // record the dirty KVs written by a committable TX into toucchedSet
 			rwList.updateTouchedSet(touchedSet)
+			if exact != nil && runner.Status != types.TX_NONCE_TOO_LARGE {
+				exact.commit(runner, touchedSet, sysDelta, isAdd, mergeSys)
+			}
 		}
-		if exec.checkRWInLoading {
-			exec.rwListMap[Runners[idx].Tx.HashID] = rwList
+		if recordRWList {
+			exec.rwListMap[runner.Tx.HashID] = rwList
 			rwList = newRWList()
 		} else {
 			rwList.reset()
@@ -676,35 +1008,9 @@ func (exec *txEngine) checkTxDepsAndUptStandbyQ(txRange *TxRange, txBundle, igno
 	}
 	idxChan <- indexAndBool{-1, false}
 	wg.Wait()
-
-	trunk := exec.cleanCtx.Rbt.GetBaseStore()
-	trunk.Update(func(store storetypes.SetDeleter) {
-		for idx, tx := range txBundle {
-			status := Runners[idx].Status
-			k := types.GetStandbyTxKey(txRange.start)
-			txRange.start++
-			store.Delete(k)
-			if status == types.FAILED_TO_COMMIT || status == types.TX_NONCE_TOO_LARGE {
-				newK := types.GetStandbyTxKey(txRange.end)
-				txRange.end++
-				store.Set(newK, tx.ToBytes()) // insert the failed TXs back into standby queue
-				Runners[idx] = nil
-			} else if status == types.ACCOUNT_NOT_EXIST || status == types.TX_NONCE_TOO_SMALL {
-				//collect invalid tx`s all gas
-				exec.cumulativeGasUsed += Runners[idx].Tx.Gas
-				exec.cumulativeGasFee.Add(exec.cumulativeGasFee, Runners[idx].GetGasFee())
-				Runners[idx] = nil
-			}
-		}
-		for _, tx := range ignoreList {
-			k := types.GetStandbyTxKey(txRange.start)
-			store.Delete(k)
-			txRange.start++
-			newK := types.GetStandbyTxKey(txRange.end)
-			txRange.end++
-			store.Set(newK, tx.ToBytes())
-		}
-	})
+	if exact != nil {
+		exact.writeMergedSystemBalance(exec)
+	}
 }
 
 // Fill 'exec.committedTxs' with 'committableRunnerList'
@@ -713,7 +1019,7 @@ func (exec *txEngine) collectCommittableTxs(committableRunnerList []*TxRunner) {
 	for idx, runner := range committableRunnerList {
 		exec.cumulativeGasUsed += runner.GasUsed
 		exec.cumulativeFeeRefund.Add(exec.cumulativeFeeRefund, &runner.FeeRefund)
-		exec.cumulativeGasFee.Add(exec.cumulativeGasFee, runner.GetGasFee())
+		exec.collectGasFee(runner)
 		tx := &types.Transaction{
 			Hash:              runner.Tx.HashID,
 			TransactionIndex:  int64(idx),
@@ -740,6 +1046,9 @@ func (exec *txEngine) collectCommittableTxs(committableRunnerList []*TxRunner) {
 		if StatusIsFailure(runner.Status) {
 			tx.Status = gethtypes.ReceiptStatusFailed
 		}
+		if StatusIsRevert(runner.Status) {
+			tx.RevertReason = types.DecodeRevertReason(tx.OutData, nil)
+		}
 		tx.Logs = make([]types.Log, len(runner.Logs))
 		for i, log := range runner.Logs {
 			copy(tx.Logs[i].Address[:], log.Address[:])
@@ -759,6 +1068,14 @@ func (exec *txEngine) collectCommittableTxs(committableRunnerList []*TxRunner) {
 			tx.Logs[i].Removed = false
 		}
 		tx.LogsBloom = LogsBloom(tx.Logs)
+		if exec.gasProfile != nil {
+			if profiler, ok := runner.Tracer.(*GasProfiler); ok {
+				exec.gasProfile.Merge(profiler.Profile())
+			}
+			if err := exec.gasProfile.AddTransaction(tx); err != nil {
+				exec.logger.Error("collectCommittableTxs: cannot profile", "hash", common.Hash(tx.Hash).String(), "err", err)
+			}
+		}
 		exec.committedTxs = append(exec.committedTxs, tx)
 	}
 }
@@ -828,11 +1145,11 @@ func (exec *txEngine) CollectedTxsCount() int {
 	return len(exec.txList)
 }
 
-func (exec *txEngine) GasUsedInfo() (gasUsed uint64, feeRefund, gasFee uint256.Int) {
+func (exec *txEngine) GasUsedInfo() (gasUsed uint64, feeRefund, gasFee, burntFee uint256.Int) {
 	if exec.cumulativeGasFee == nil {
-		return exec.cumulativeGasUsed, *exec.cumulativeFeeRefund, uint256.Int{}
+		return exec.cumulativeGasUsed, *exec.cumulativeFeeRefund, uint256.Int{}, uint256.Int{}
 	}
-	return exec.cumulativeGasUsed, *exec.cumulativeFeeRefund, *exec.cumulativeGasFee
+	return exec.cumulativeGasUsed, *exec.cumulativeFeeRefund, *exec.cumulativeGasFee, *exec.cumulativeBurntFee
 }
 
 func (exec *txEngine) StandbyQLen() int {
@@ -862,6 +1179,14 @@ func SubSystemAccBalance(ctx *types.Context, amount *uint256.Int) error {
 	return updateBalance(ctx, systemContractAddress, amount, false)
 }
 
+func TransferFromSystemAccToBlackHoleAcc(ctx *types.Context, amount *uint256.Int) error {
+	err := updateBalance(ctx, systemContractAddress, amount, false)
+	if err != nil {
+		return err
+	}
+	return updateBalance(ctx, blackHoleContractAddress, amount, true)
+}
+
 func TransferFromSenderAccToBlackHoleAcc(ctx *types.Context, sender common.Address, amount *uint256.Int) error {
 	err := updateBalance(ctx, sender, amount, false)
 	if err != nil {
@@ -2,6 +2,7 @@ package ebp
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"math/big"
 	"math/rand"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	gethcrypto "github.com/ethereum/go-ethereum/crypto"
 	"github.com/holiman/uint256"
 	"github.com/stretchr/testify/require"
@@ -90,17 +92,17 @@ func TestTxEngine_DifferentAccount(t *testing.T) {
 	AdjustGasUsed = false
 	trunk, root := prepareTruck()
 	defer closeTestCtx(root)
-	e := NewEbpTxExec(1, 100, 2, 10, &testcase.DumbSigner{}, log.NewNopLogger())
+	e := NewEbpTxExec(1, 100, 2, 10, &testcase.DumbSigner{}, nil, log.NewNopLogger())
 	e.SetContext(prepareCtx(trunk))
 	txs := prepareAccAndTx(e)
 	e.SetContext(prepareCtx(trunk))
 	for _, tx := range txs {
 		e.CollectTx(tx)
 	}
-	e.Prepare(0, 0, DefaultTxGasLimit)
+	e.Prepare(0, 0, DefaultTxGasLimit, nil)
 	e.SetContext(prepareCtx(trunk))
 	startKey, endKey := e.getStandbyQueueRange()
-	txsStandby, _ := e.loadStandbyTxs(&TxRange{
+	txsStandby, _, _ := e.loadStandbyTxs(&TxRange{
 		start: startKey,
 		end:   endKey,
 	})
@@ -128,7 +130,7 @@ func TestTxEngine_CheckRWInLoading(t *testing.T) {
 	AdjustGasUsed = false
 	trunk, root := prepareTruck()
 	defer closeTestCtx(root)
-	e := NewEbpTxExec(5, 100, 2, 10, &testcase.DumbSigner{}, log.NewNopLogger())
+	e := NewEbpTxExec(5, 100, 2, 10, &testcase.DumbSigner{}, nil, log.NewNopLogger())
 	e.SetContext(prepareCtx(trunk))
 	txs := prepareAccAndTx(e)
 	tx2, _ := gethtypes.NewTransaction(0, to1, big.NewInt(100), 100000, big.NewInt(1), nil).WithSignature(e.signer, from2.Bytes())
@@ -140,10 +142,10 @@ func TestTxEngine_CheckRWInLoading(t *testing.T) {
 		e.CollectTx(tx)
 	}
 	e.checkRWInLoading = true
-	e.Prepare(0, 0, DefaultTxGasLimit)
+	e.Prepare(0, 0, DefaultTxGasLimit, nil)
 	e.SetContext(prepareCtx(trunk))
 	startKey, endKey := e.getStandbyQueueRange()
-	txsStandby, _ := e.loadStandbyTxs(&TxRange{
+	txsStandby, _, _ := e.loadStandbyTxs(&TxRange{
 		start: startKey,
 		end:   endKey,
 	})
@@ -166,6 +168,571 @@ func TestTxEngine_CheckRWInLoading(t *testing.T) {
 	require.Equal(t, true, startKey == endKey && endKey == 6)
 }
 
+func TestTxEngine_DAGScheduler(t *testing.T) {
+	AdjustGasUsed = false
+	trunk, root := prepareTruck()
+	defer closeTestCtx(root)
+	e := NewEbpTxExec(5, 100, 2, 10, &testcase.DumbSigner{}, nil, log.NewNopLogger())
+	e.SetContext(prepareCtx(trunk))
+	txs := prepareAccAndTx(e)
+	tx2, _ := gethtypes.NewTransaction(0, to1, big.NewInt(100), 100000, big.NewInt(1), nil).WithSignature(e.signer, from2.Bytes())
+	txs[1] = tx2
+	tx3, _ := gethtypes.NewTransaction(0, to1, big.NewInt(100), 100000, big.NewInt(1), nil).WithSignature(e.signer, from3.Bytes())
+	txs = append(txs, tx3)
+	e.SetContext(prepareCtx(trunk))
+	for _, tx := range txs {
+		e.CollectTx(tx)
+	}
+	e.SetDAGScheduling(true)
+	e.Prepare(0, 0, DefaultTxGasLimit, nil)
+	e.SetContext(prepareCtx(trunk))
+	e.Execute(&types.BlockInfo{})
+	require.Equal(t, 3, len(e.committedTxs))
+	// all the TXs touch to1, so after the first round only one of them is ready in each round
+	require.Equal(t, 3+1+1, e.txExecutedCount)
+	report := e.ExecutionReport()
+	require.Equal(t, 3, len(report.Rounds))
+	require.Equal(t, 3, len(report.Rounds[0].Loaded))
+	require.Equal(t, 2, len(report.Rounds[0].FailedToCommit))
+	require.Equal(t, 2, len(report.Rounds[0].Conflicts))
+	require.Equal(t, 1, len(report.Rounds[1].Loaded))
+	require.Equal(t, 1, len(report.Rounds[1].Ignored))
+	require.Equal(t, 3, report.StandbyQLenBefore)
+	require.Equal(t, 0, report.StandbyQLenAfter)
+	e.SetContext(prepareCtx(trunk))
+	to1 := e.cleanCtx.GetAccount(*txs[0].To())
+	require.Equal(t, uint64(300), to1.Balance().Uint64())
+	e.cleanCtx.Close(false)
+	e.SetContext(prepareCtx(trunk))
+	startKey, endKey := e.getStandbyQueueRange()
+	require.Equal(t, true, startKey == endKey && endKey == 3)
+}
+
+func TestTxEngine_ExactKeyConflict(t *testing.T) {
+	AdjustGasUsed = false
+	for _, cfg := range []*ExactKeyConflictConfig{nil, {}, {CommutativeSystemBalance: true}} {
+		trunk, root := prepareTruck()
+		e := NewEbpTxExec(5, 100, 2, 10, &testcase.DumbSigner{}, nil, log.NewNopLogger())
+		e.SetContext(prepareCtx(trunk))
+		prepareAccAndTx(e)
+		var txs []*gethtypes.Transaction
+		for _, from := range []common.Address{from1, from2, from3} {
+			tx, _ := gethtypes.NewTransaction(0, systemContractAddress, big.NewInt(100), 100000, big.NewInt(1), nil).WithSignature(e.signer, from.Bytes())
+			txs = append(txs, tx)
+		}
+		e.SetContext(prepareCtx(trunk))
+		for _, tx := range txs {
+			e.CollectTx(tx)
+		}
+		e.SetExactKeyConflict(cfg)
+		e.Prepare(0, 0, DefaultTxGasLimit, nil)
+		e.SetContext(prepareCtx(trunk))
+		e.Execute(&types.BlockInfo{})
+		require.Equal(t, 3, len(e.committedTxs))
+		if cfg != nil && cfg.CommutativeSystemBalance {
+			require.Equal(t, 3, e.txExecutedCount) // the transfers to system account are merged
+		} else {
+			require.Equal(t, 3+1+1, e.txExecutedCount)
+		}
+		e.SetContext(prepareCtx(trunk))
+		sysAcc := e.cleanCtx.GetAccount(systemContractAddress)
+		require.Equal(t, uint64(3*100000+3*100), sysAcc.Balance().Uint64())
+		e.cleanCtx.Close(false)
+		e.Close()
+		closeTestCtx(root)
+	}
+}
+
+func TestExactKeyChecker_ShortKeyCollision(t *testing.T) {
+	// the two storage keys fall on the same short key, e.g. when one jumps over the other's slot in rabbit store
+	const shortKey = uint64(0x1234)
+	touchedSet := make(map[uint64]struct{})
+	writes := newRWList()
+	writes.add(shortKey, true)
+	writes.updateTouchedSet(touchedSet)
+	checker := (&txEngine{exactKeyConfig: &ExactKeyConflictConfig{}}).newExactKeyChecker()
+	writer := &TxRunner{trackKeys: true, accessedKeys: []accessedKey{{key: "key-a", class: storageKeyClass, isWrite: true}}}
+	checker.commit(writer, touchedSet, nil, false, false)
+
+	reads := newRWList()
+	reads.add(shortKey, false)
+	require.True(t, reads.conflictsWith(touchedSet)) // a conflict without exact keys
+	reader := &TxRunner{trackKeys: true, accessedKeys: []accessedKey{{key: "key-b", class: storageKeyClass}}}
+	require.False(t, checker.checksShortKey(reader, shortKey, false, false))
+	require.Nil(t, checker.conflictingKey(reader, false))
+
+	// reading the written key itself is still a conflict
+	reader.accessedKeys = append(reader.accessedKeys, accessedKey{key: "key-a", class: storageKeyClass})
+	require.Equal(t, []byte("key-a"), checker.conflictingKey(reader, false))
+	// so is writing the same short key, which is needed to write back rabbit stores safely
+	require.True(t, checker.checksShortKey(reader, shortKey, true, false))
+}
+
+func TestMergeInQueueOrder(t *testing.T) {
+	tx := func(from common.Address, nonce uint64) types.TxToRun {
+		return types.TxToRun{BasicTx: types.BasicTx{From: from, Nonce: nonce}}
+	}
+	// the standby queue is [A1, B1, A2, C1], and A2 is ignored when loading
+	txBundle := []types.TxToRun{tx(from1, 1), tx(from2, 1), tx(from3, 1)}
+	ignoreList := []types.TxToRun{tx(from1, 2)}
+	ignoredOffsets := []int{2}
+	require.Equal(t, []types.TxToRun{tx(from1, 1), tx(from2, 1), tx(from1, 2), tx(from3, 1)},
+		mergeInQueueOrder(txBundle, []bool{true, true, true}, ignoreList, ignoredOffsets))
+	require.Equal(t, []types.TxToRun{tx(from1, 1), tx(from1, 2), tx(from3, 1)},
+		mergeInQueueOrder(txBundle, []bool{true, false, true}, ignoreList, ignoredOffsets))
+	// ignored TXs at the head and the tail
+	require.Equal(t, []types.TxToRun{tx(from1, 2), tx(from2, 1), tx(from3, 2)},
+		mergeInQueueOrder(txBundle[1:2], []bool{true}, []types.TxToRun{tx(from1, 2), tx(from3, 2)}, []int{0, 2}))
+}
+
+func TestTxEngine_BlockGasLimit(t *testing.T) {
+	AdjustGasUsed = false
+	for _, parallelNum := range []int{1, 4} {
+		trunk, root := prepareTruck()
+		e := NewEbpTxExec(5, 100, parallelNum, 10, &testcase.DumbSigner{}, nil, log.NewNopLogger())
+		e.SetContext(prepareCtx(trunk))
+		txs := prepareAccAndTx(e)
+		tx3, _ := gethtypes.NewTransaction(0, common.HexToAddress("0x30"), big.NewInt(100), 100000, big.NewInt(1), nil).WithSignature(e.signer, from3.Bytes())
+		txs = append(txs, tx3)
+		e.SetContext(prepareCtx(trunk))
+		for _, tx := range txs {
+			e.CollectTx(tx)
+		}
+		e.Prepare(0, 0, DefaultTxGasLimit, nil)
+		e.SetContext(prepareCtx(trunk))
+		e.Execute(&types.BlockInfo{GasLimit: 2 * 21000})
+		require.Equal(t, 2, len(e.committedTxs))
+		require.Equal(t, txs[0].Hash(), common.Hash(e.committedTxs[0].Hash))
+		require.Equal(t, txs[1].Hash(), common.Hash(e.committedTxs[1].Hash))
+		e.SetContext(prepareCtx(trunk))
+		require.Nil(t, e.cleanCtx.GetAccount(*tx3.To()))
+		e.cleanCtx.Close(false)
+		e.SetContext(prepareCtx(trunk))
+		startKey, endKey := e.getStandbyQueueRange()
+		require.Equal(t, uint64(2), startKey) // the leftover stays at the head of standby queue
+		require.Equal(t, uint64(3), endKey)
+		e.cleanCtx.Close(false)
+		closeTestCtx(root)
+	}
+}
+
+func TestTxEngine_GasPriceTxOrderer(t *testing.T) {
+	AdjustGasUsed = false
+	trunk, root := prepareTruck()
+	defer closeTestCtx(root)
+	e := NewEbpTxExec(5, 100, 2, 10, &testcase.DumbSigner{}, GasPriceTxOrderer{}, log.NewNopLogger())
+	e.SetContext(prepareCtx(trunk))
+	txs := prepareAccAndTx(e)
+	tx3, _ := gethtypes.NewTransaction(1, to1, big.NewInt(100), 100000, big.NewInt(1), nil).WithSignature(e.signer, from1.Bytes())
+	tx4, _ := gethtypes.NewTransaction(0, to1, big.NewInt(100), 100000, big.NewInt(3), nil).WithSignature(e.signer, from3.Bytes())
+	txs = append(txs, tx3, tx4)
+	e.SetContext(prepareCtx(trunk))
+	for _, tx := range txs {
+		e.CollectTx(tx)
+	}
+	e.Prepare(0, 0, DefaultTxGasLimit, nil)
+	e.SetContext(prepareCtx(trunk))
+	startKey, endKey := e.getStandbyQueueRange()
+	txsStandby, _, _ := e.loadStandbyTxs(&TxRange{
+		start: startKey,
+		end:   endKey,
+	})
+	e.cleanCtx.Close(false)
+	require.Equal(t, 4, len(txsStandby))
+	// from3 has the highest gas price; from1 and from2 have the same gas price and are ordered by address,
+	// while the two TXs of from1 stay back-to-back
+	require.Equal(t, txs[3].Hash(), txsStandby[0].HashID)
+	require.Equal(t, txs[1].Hash(), txsStandby[1].HashID)
+	require.Equal(t, txs[0].Hash(), txsStandby[2].HashID)
+	require.Equal(t, txs[2].Hash(), txsStandby[3].HashID)
+}
+
+func TestTxEngine_GasPriceRejections(t *testing.T) {
+	AdjustGasUsed = false
+	trunk, root := prepareTruck()
+	defer closeTestCtx(root)
+	e := NewEbpTxExec(5, 100, 2, 10, &testcase.DumbSigner{}, nil, log.NewNopLogger())
+	e.SetContext(prepareCtx(trunk))
+	prepareAccAndTx(e)
+	hugePrice := new(big.Int).Lsh(big.NewInt(1), 64)
+	tx1, _ := gethtypes.NewTransaction(0, to1, big.NewInt(100), 100000, hugePrice, nil).WithSignature(e.signer, from1.Bytes())
+	tx2, _ := gethtypes.NewTransaction(0, to2, big.NewInt(100), 100000, big.NewInt(1), nil).WithSignature(e.signer, from2.Bytes())
+	e.SetContext(prepareCtx(trunk))
+	e.CollectTx(tx1)
+	e.CollectTx(tx2)
+	e.Prepare(0, 5, DefaultTxGasLimit, nil)
+	require.Equal(t, 2, len(e.committedTxs))
+	rejections := make(map[common.Hash]*types.TxRejection)
+	for _, tx := range e.committedTxs {
+		rejections[tx.Hash] = tx.Rejection
+	}
+	require.Equal(t, types.REJECTED_GAS_PRICE_TOO_HIGH, rejections[tx1.Hash()].Code)
+	require.Equal(t, "gas price out of range", rejections[tx1.Hash()].String())
+	require.Equal(t, types.REJECTED_GAS_PRICE_TOO_LOW, rejections[tx2.Hash()].Code)
+	require.Equal(t, uint64(5), rejections[tx2.Hash()].MinGasPrice)
+}
+
+func TestTxEngine_StandbyQ(t *testing.T) {
+	AdjustGasUsed = false
+	trunk, root := prepareTruck()
+	defer closeTestCtx(root)
+	e := NewEbpTxExec(5, 100, 2, 10, &testcase.DumbSigner{}, nil, log.NewNopLogger())
+	e.SetContext(prepareCtx(trunk))
+	txs := prepareAccAndTx(e)
+	e.SetContext(prepareCtx(trunk))
+	for _, tx := range txs {
+		e.CollectTx(tx)
+	}
+	e.Prepare(0, 0, DefaultTxGasLimit, nil)
+	e.SetContext(prepareCtx(trunk))
+	all := e.GetStandbyTxs(5, 0, 10)
+	require.Equal(t, 2, len(all))
+	require.Equal(t, uint64(5), all[0].Age)
+	require.False(t, all[0].TooOld)
+	page := e.GetStandbyTxs(5, 1, 10)
+	require.Equal(t, 1, len(page))
+	require.Equal(t, all[1], page[0])
+	require.Equal(t, 0, len(e.GetStandbyTxs(5, 2, 10)))
+	stx, found := e.GetStandbyTxByHash(20, txs[1].Hash())
+	require.True(t, found)
+	require.Equal(t, all[1].Position, stx.Position)
+	require.True(t, stx.TooOld)
+	_, found = e.GetStandbyTxByHash(20, common.Hash{})
+	require.False(t, found)
+	bySender := e.GetStandbyTxsBySender(5, from2)
+	require.Equal(t, 1, len(bySender))
+	require.Equal(t, txs[1].Hash(), bySender[0].Tx.HashID)
+
+	e.currentBlock = &types.BlockInfo{Number: int64(types.TOO_OLD_THRESHOLD) + 1}
+	require.Equal(t, 2, e.PurgeStandbyQ())
+	require.Equal(t, 0, e.StandbyQLen())
+	require.Equal(t, 2, len(e.committedTxs))
+	require.Equal(t, "too old", e.committedTxs[0].StatusStr)
+	require.Equal(t, types.REJECTED_TOO_OLD, e.committedTxs[0].Rejection.Code)
+	require.Equal(t, 0, e.PurgeStandbyQ())
+	e.cleanCtx.Close(false)
+}
+
+func TestTxEngine_BlockedSender(t *testing.T) {
+	AdjustGasUsed = false
+	trunk, root := prepareTruck()
+	defer closeTestCtx(root)
+	e := NewEbpTxExec(5, 100, 2, 10, &testcase.DumbSigner{}, nil, log.NewNopLogger())
+	e.SetContext(prepareCtx(trunk))
+	txs := prepareAccAndTx(e)
+	ctx := prepareCtx(trunk)
+	MigrateLegacyBlockedSender(ctx)
+	ctx.SetBlockedSender(from2, 0)
+	ctx.SetBlockedSender(from3, 100) // not activated yet
+	ctx.Close(true)
+	e.SetContext(prepareCtx(trunk))
+	require.True(t, e.cleanCtx.IsBlockedSender(LegacyBlockedSender))
+	require.True(t, e.cleanCtx.IsBlockedSender(from2))
+	require.False(t, e.cleanCtx.IsBlockedSender(from3))
+	require.False(t, e.cleanCtx.IsBlockedSender(from1))
+	for _, tx := range txs {
+		e.CollectTx(tx)
+	}
+	e.Prepare(0, 0, DefaultTxGasLimit, nil)
+	require.Equal(t, 1, len(e.committedTxs))
+	require.Equal(t, txs[1].Hash(), common.Hash(e.committedTxs[0].Hash))
+	require.Equal(t, "Blocked Account", e.committedTxs[0].StatusStr)
+	e.SetContext(prepareCtx(trunk))
+	require.Equal(t, 1, e.StandbyQLen())
+	e.cleanCtx.DeleteBlockedSender(from2)
+	require.False(t, e.cleanCtx.IsBlockedSender(from2))
+	e.cleanCtx.Close(false)
+}
+
+func TestTxEngine_LegacyBlockedSender(t *testing.T) {
+	AdjustGasUsed = false
+	trunk, root := prepareTruck()
+	defer closeTestCtx(root)
+	e := NewEbpTxExec(5, 100, 2, 10, &testcase.DumbSigner{}, nil, log.NewNopLogger())
+	e.SetContext(prepareCtx(trunk))
+	prepareAccAndTx(e)
+	ctx := prepareCtx(trunk)
+	acc := types.ZeroAccountInfo()
+	acc.UpdateBalance(uint256.NewInt(10000_0000_0000))
+	ctx.SetAccount(LegacyBlockedSender, acc)
+	ctx.Close(true)
+	tx, _ := gethtypes.NewTransaction(0, to1, big.NewInt(100), 100000, big.NewInt(1), nil).WithSignature(e.signer,
+		LegacyBlockedSender.Bytes())
+
+	// blocked without MigrateLegacyBlockedSender
+	e.SetContext(prepareCtx(trunk))
+	e.CollectTx(tx)
+	e.Prepare(0, 0, DefaultTxGasLimit, nil)
+	require.Equal(t, 1, len(e.committedTxs))
+	require.Equal(t, types.REJECTED_BLOCKED_SENDER, e.committedTxs[0].Rejection.Code)
+
+	// the entry in the blocklist takes precedence
+	ctx = prepareCtx(trunk)
+	ctx.SetBlockedSender(LegacyBlockedSender, 100)
+	ctx.Close(true)
+	e.committedTxs = e.committedTxs[:0]
+	e.SetContext(prepareCtx(trunk))
+	e.CollectTx(tx)
+	e.Prepare(0, 0, DefaultTxGasLimit, nil)
+	require.Equal(t, 0, len(e.committedTxs))
+	e.SetContext(prepareCtx(trunk))
+	require.Equal(t, 1, e.StandbyQLen())
+	e.cleanCtx.Close(false)
+}
+
+func TestTxEngine_DynamicFeeTx(t *testing.T) {
+	AdjustGasUsed = false
+	trunk, root := prepareTruck()
+	defer closeTestCtx(root)
+	e := NewEbpTxExec(5, 100, 2, 10, &testcase.DumbSigner{}, nil, log.NewNopLogger())
+	e.SetContext(prepareCtx(trunk))
+	prepareAccAndTx(e)
+	london := types.NewForkSchedule().Set(types.BerlinFork, 0).Set(types.LondonFork, 0)
+	newTx := func(from common.Address, feeCap int64) *gethtypes.Transaction {
+		tx, _ := gethtypes.NewTx(&gethtypes.DynamicFeeTx{ChainID: big.NewInt(1), Nonce: 0, To: &to1,
+			Value: big.NewInt(100), Gas: 100000, GasTipCap: big.NewInt(2), GasFeeCap: big.NewInt(feeCap)},
+		).WithSignature(e.signer, from.Bytes())
+		return tx
+	}
+
+	// rejected before the London fork
+	e.SetContext(prepareCtx(trunk))
+	e.CollectTx(newTx(from1, 100))
+	e.Prepare(0, 0, DefaultTxGasLimit, nil)
+	require.Equal(t, types.REJECTED_TX_TYPE_NOT_ALLOWED, e.committedTxs[0].Rejection.Code)
+
+	// a brand-new engine, which has never executed a block, uses the base fee given to Prepare
+	e.Close()
+	e = NewEbpTxExec(5, 100, 2, 10, &testcase.DumbSigner{}, nil, log.NewNopLogger())
+	defer e.Close()
+	e.SetContext(prepareCtx(trunk))
+	e.cleanCtx.SetForkSchedule(london)
+	e.CollectTx(newTx(from1, 100))
+	e.CollectTx(newTx(from2, 5)) // cannot pay the base fee
+	e.Prepare(0, 0, DefaultTxGasLimit, uint256.NewInt(10))
+	require.Equal(t, 1, len(e.committedTxs))
+	require.Equal(t, types.REJECTED_GAS_PRICE_TOO_LOW, e.committedTxs[0].Rejection.Code)
+	require.Equal(t, uint64(10), e.committedTxs[0].Rejection.MinGasPrice)
+
+	e.SetContext(prepareCtx(trunk))
+	e.cleanCtx.SetForkSchedule(london)
+	e.Execute(&types.BlockInfo{BaseFee: uint256.NewInt(10).Bytes32()})
+	require.Equal(t, 1, len(e.committedTxs))
+	require.Equal(t, uint256.NewInt(12).Bytes32(), e.committedTxs[0].GasPrice) // min(100, 10+2)
+	gasUsed, _, gasFee, burntFee := e.GasUsedInfo()
+	require.Equal(t, uint64(21000), gasUsed)
+	require.Equal(t, uint64(21000*2), gasFee.Uint64())
+	require.Equal(t, uint64(21000*10), burntFee.Uint64())
+	e.SetContext(prepareCtx(trunk))
+	require.Equal(t, uint64(21000*10), GetBlackHoleBalance(e.cleanCtx).Uint64())
+	require.Equal(t, uint64(10000_0000_0000-21000*12-100), e.cleanCtx.GetAccount(from1).Balance().Uint64())
+	e.cleanCtx.Close(false)
+
+	// like geth, the tip cannot be higher than the fee cap, even if the fee cap is enough
+	e.committedTxs = e.committedTxs[:0]
+	e.SetContext(prepareCtx(trunk))
+	e.cleanCtx.SetForkSchedule(london)
+	e.CollectTx(newTx(from3, 1))
+	e.Prepare(0, 0, DefaultTxGasLimit, uint256.NewInt(0))
+	require.Equal(t, 1, len(e.committedTxs))
+	require.Equal(t, types.REJECTED_TIP_ABOVE_FEE_CAP, e.committedTxs[0].Rejection.Code)
+	require.Equal(t, "max priority fee per gas higher than max fee per gas", e.committedTxs[0].StatusStr)
+
+	// BASEFEE PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+	code := hexToBytes("4860005260206000f3")
+	ctx := prepareCtx(trunk)
+	defer ctx.Close(false)
+	ctx.SetForkSchedule(london)
+	runner := NewTxRunner(ctx, &types.TxToRun{BasicTx: types.BasicTx{From: from1, To: to2, Gas: 100000}})
+	runner.ForRpc = true
+	require.NoError(t, runner.SetStateOverride(StateOverride{to2: {Code: &code}}))
+	e.RunTxForRpc(&types.BlockInfo{BaseFee: uint256.NewInt(10).Bytes32()}, false, runner)
+	require.Equal(t, "success", StatusToStr(runner.Status))
+	require.Equal(t, uint64(10), uint256.NewInt(0).SetBytes(runner.OutData).Uint64())
+}
+
+func TestShanghaiFork(t *testing.T) {
+	trunk, root := prepareTruck()
+	defer closeTestCtx(root)
+	e := NewEbpTxExec(5, 100, 2, 10, &testcase.DumbSigner{}, nil, log.NewNopLogger())
+	e.SetContext(prepareCtx(trunk))
+	prepareAccAndTx(e)
+	shanghai := types.NewForkSchedule().Set(types.BerlinFork, 0).Set(types.LondonFork, 0).Set(types.ShanghaiFork, 0)
+	run := func(forks *types.ForkSchedule, to common.Address, data []byte) *TxRunner {
+		// PUSH1 7 PUSH0 MSTORE PUSH1 32 PUSH0 RETURN
+		code := hexToBytes("60075f5260205ff3")
+		ctx := prepareCtx(trunk)
+		defer ctx.Close(false)
+		ctx.SetForkSchedule(forks)
+		runner := NewTxRunner(ctx, &types.TxToRun{BasicTx: types.BasicTx{From: from1, To: to, Gas: 1000000, Data: data}})
+		runner.ForRpc = true
+		require.NoError(t, runner.SetStateOverride(StateOverride{to2: {Code: &code}}))
+		e.RunTxForRpc(&types.BlockInfo{}, false, runner)
+		return runner
+	}
+
+	runner := run(nil, to2, nil)
+	require.Equal(t, "undefined-instruction", StatusToStr(runner.Status))
+	runner = run(shanghai, to2, nil)
+	require.Equal(t, "success", StatusToStr(runner.Status))
+	require.Equal(t, uint64(7), uint256.NewInt(0).SetBytes(runner.OutData).Uint64())
+
+	// EIP-3860: the init code is no longer than twice of the max code size
+	initCode := make([]byte, 2*types.DefaultMaxCodeSize+1)
+	runner = run(shanghai, common.Address{}, initCode)
+	require.Equal(t, "out-of-gas", StatusToStr(runner.Status))
+	shanghai.MaxCodeSize = types.DefaultMaxCodeSize * 2
+	runner = run(shanghai, common.Address{}, initCode)
+	require.Equal(t, "success", StatusToStr(runner.Status))
+}
+
+func TestCancunTransientStorage(t *testing.T) {
+	trunk, root := prepareTruck()
+	defer closeTestCtx(root)
+	e := NewEbpTxExec(5, 100, 2, 10, &testcase.DumbSigner{}, nil, log.NewNopLogger())
+	e.SetContext(prepareCtx(trunk))
+	prepareAccAndTx(e)
+	cancun := types.NewForkSchedule().Set(types.BerlinFork, 0).Set(types.LondonFork, 0).
+		Set(types.ShanghaiFork, 0).Set(types.CancunFork, 0)
+	// TSTORE(0, 5), DELEGATECALL to1, then return TLOAD(0)
+	code := hexToBytes("600560005d600080808060105af45060005c60005260206000f3")
+	run := func(forks *types.ForkSchedule, calleeCode []byte) *TxRunner {
+		ctx := prepareCtx(trunk)
+		defer ctx.Close(false)
+		ctx.SetForkSchedule(forks)
+		runner := NewTxRunner(ctx, &types.TxToRun{BasicTx: types.BasicTx{From: from1, To: to2, Gas: 1000000}})
+		runner.ForRpc = true
+		require.NoError(t, runner.SetStateOverride(StateOverride{to1: {Code: &calleeCode}, to2: {Code: &code}}))
+		e.RunTxForRpc(&types.BlockInfo{}, false, runner)
+		return runner
+	}
+
+	// TSTORE(0, 9) STOP
+	runner := run(nil, hexToBytes("600960005d00"))
+	require.Equal(t, "undefined-instruction", StatusToStr(runner.Status))
+	runner = run(cancun, hexToBytes("600960005d00"))
+	require.Equal(t, "success", StatusToStr(runner.Status))
+	require.Equal(t, uint64(9), uint256.NewInt(0).SetBytes(runner.OutData).Uint64())
+	// TSTORE(0, 9) REVERT(0, 0)
+	runner = run(cancun, hexToBytes("600960005d60008081fd"))
+	require.Equal(t, "success", StatusToStr(runner.Status))
+	require.Equal(t, uint64(5), uint256.NewInt(0).SetBytes(runner.OutData).Uint64())
+}
+
+type dumbSystemContract struct {
+	addr common.Address
+}
+
+func (c *dumbSystemContract) RequiredGas(input []byte) uint64           { return 0 }
+func (c *dumbSystemContract) Run(input []byte) ([]byte, error)          { return nil, nil }
+func (c *dumbSystemContract) Init(ctx *types.Context)                   {}
+func (c *dumbSystemContract) IsSystemContract(addr common.Address) bool { return addr == c.addr }
+func (c *dumbSystemContract) Execute(ctx *types.Context, currBlock *types.BlockInfo, tx *types.TxToRun) (int, []types.EvmLog, uint64, []byte) {
+	return 0, nil, 0, nil
+}
+
+func TestTxEngine_RunnerTables(t *testing.T) {
+	e1 := NewEbpTxExec(5, 10, 2, 10, &testcase.DumbSigner{}, nil, log.NewNopLogger())
+	e2 := NewEbpTxExec(5, 20, 2, 10, &testcase.DumbSigner{}, nil, log.NewNopLogger())
+	defer e2.Close()
+	require.NotEqual(t, e1.runnerTable.id, e2.runnerTable.id)
+	require.Equal(t, 10, len(getRunnerTable(e1.runnerTable.handler(0)).runners))
+	require.Equal(t, 20, len(getRunnerTable(e2.runnerTable.handler(RpcRunnersIdStart)).runners))
+	sysAddr := common.HexToAddress("0x2710")
+	e1.RegisterPredefinedContract(nil, sysAddr, &dumbSystemContract{addr: sysAddr})
+	require.Equal(t, 1, len(e1.runnerTable.predefinedContracts))
+	require.Equal(t, 0, len(e2.runnerTable.predefinedContracts))
+	e1.Close()
+	require.Nil(t, runnerTableRegistry[e1.runnerTable.id])
+	e3 := NewEbpTxExec(5, 10, 2, 10, &testcase.DumbSigner{}, nil, log.NewNopLogger())
+	defer e3.Close()
+	require.Equal(t, e1.runnerTable.id, e3.runnerTable.id) // the released slot is reused
+}
+
+func TestTxEngine_RpcQueue(t *testing.T) {
+	e := NewEbpTxExec(5, 10, 2, 10, &testcase.DumbSigner{}, nil, log.NewNopLogger())
+	defer e.Close()
+	for i := 0; i < RpcRunnersCount; i++ { // occupy all the RPC runners
+		idx, err := e.runnerTable.acquireRpcRunner(context.Background(), true)
+		require.NoError(t, err)
+		require.Equal(t, i, idx)
+	}
+	e.SetRpcLimits(0, -1)
+	_, err := e.RunTxForRpcWithContext(context.Background(), &types.BlockInfo{}, false, nil)
+	require.Equal(t, ErrRpcQueueFull, err)
+	e.SetRpcLimits(1, -1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = e.RunTxForRpcWithContext(ctx, &types.BlockInfo{}, false, nil)
+	require.Equal(t, context.DeadlineExceeded, err)
+	require.Equal(t, int32(0), e.runnerTable.rpcWaiting)
+	e.runnerTable.releaseRpcRunner(3)
+	idx, err := e.runnerTable.acquireRpcRunner(context.Background(), true)
+	require.NoError(t, err)
+	require.Equal(t, 3, idx)
+}
+
+func TestTxEngine_RpcAbort(t *testing.T) {
+	e, runner := newRpcRunnerWithOverride(t)
+	e.SetRpcLimits(1, -1)
+	loop := hexToBytes("5b600056") // JUMPDEST PUSH1 0 JUMP
+	require.NoError(t, runner.SetStateOverride(StateOverride{to1: {Code: &loop}}))
+	runner.Tx.Gas = 1000_000_000
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, err := e.RunTxForRpcWithContext(ctx, &types.BlockInfo{}, false, runner)
+	require.Equal(t, context.Canceled, err)
+	require.Equal(t, "internal-error", StatusToStr(runner.Status))
+
+	e.SetRpcLimits(1, 1000)
+	runner = newCounterRunner(t, runner.Ctx)
+	require.NoError(t, runner.SetStateOverride(StateOverride{to1: {Code: &loop}}))
+	runner.Tx.Gas = 1000_000_000
+	_, err = e.RunTxForRpcWithContext(context.Background(), &types.BlockInfo{}, false, runner)
+	require.Equal(t, ErrStepBudgetExceeded, err)
+	require.Equal(t, "internal-error", StatusToStr(runner.Status))
+	// the budget is enough for a simple call
+	counter := newCounterRunner(t, runner.Ctx)
+	_, err = e.RunTxForRpcWithContext(context.Background(), &types.BlockInfo{}, false, counter)
+	require.NoError(t, err)
+	require.Equal(t, common.BigToHash(big.NewInt(7)).Bytes(), counter.OutData)
+}
+
+func TestTxEngine_SimulateBlock(t *testing.T) {
+	AdjustGasUsed = false
+	trunk, root := prepareTruck()
+	defer closeTestCtx(root)
+	e := NewEbpTxExec(5, 100, 2, 10, &testcase.DumbSigner{}, nil, log.NewNopLogger())
+	e.SetContext(prepareCtx(trunk))
+	txs := prepareAccAndTx(e)
+	tx3, _ := gethtypes.NewTransaction(5, to1, big.NewInt(100), 100000, big.NewInt(1), nil).WithSignature(e.signer, from3.Bytes())
+	txs = append(txs, tx3)
+	e.SetContext(prepareCtx(trunk))
+	res := e.SimulateBlock(txs, &types.BlockInfo{Number: 1}, 0)
+	require.Equal(t, 1, len(res.InvalidTxs))
+	require.Equal(t, "incorrect nonce", res.InvalidTxs[0].StatusStr)
+	require.Equal(t, types.REJECTED_INCORRECT_NONCE, res.InvalidTxs[0].Rejection.Code)
+	require.Equal(t, uint64(5), res.InvalidTxs[0].Rejection.ActualNonce)
+	require.Equal(t, uint64(0), res.InvalidTxs[0].Rejection.ExpectedNonce)
+	require.Equal(t, 2, len(res.Receipts))
+	require.Equal(t, uint64(2*21000), res.GasUsed)
+	require.Equal(t, 0, len(res.StandbyTxs))
+	var to1Acc *types.AccountInfo
+	for _, change := range res.StateChanges {
+		if bytes.Equal(change.Key, types.GetAccountKey(to1)) {
+			to1Acc = types.NewAccountInfo(change.Value)
+		}
+	}
+	require.NotNil(t, to1Acc)
+	require.Equal(t, uint64(100), to1Acc.Balance().Uint64())
+	// nothing is written back
+	require.Nil(t, e.cleanCtx.GetAccount(to1))
+	require.Equal(t, uint64(10000_0000_0000), e.cleanCtx.GetAccount(from1).Balance().Uint64())
+	require.Equal(t, 0, e.StandbyQLen())
+	e.cleanCtx.Close(false)
+}
+
 /*
 testcase:
 account1 send txs(nonce): 0, 0, 2, 1, 2
@@ -176,7 +743,7 @@ func TestTxEngine_SameAccount(t *testing.T) {
 	AdjustGasUsed = false
 	trunk, root := prepareTruck()
 	defer closeTestCtx(root)
-	e := NewEbpTxExec(5, 100, 2, 10, &testcase.DumbSigner{}, log.NewNopLogger())
+	e := NewEbpTxExec(5, 100, 2, 10, &testcase.DumbSigner{}, nil, log.NewNopLogger())
 	e.SetContext(prepareCtx(trunk))
 	txs := prepareAccAndTx(e)
 	e.SetContext(prepareCtx(trunk))
@@ -193,10 +760,10 @@ func TestTxEngine_SameAccount(t *testing.T) {
 	tx6, _ := gethtypes.NewTransaction(2, to1, big.NewInt(104), 100000, big.NewInt(1), nil).WithSignature(e.signer, from1.Bytes())
 	e.CollectTx(tx6)
 
-	e.Prepare(0, 0, DefaultTxGasLimit)
+	e.Prepare(0, 0, DefaultTxGasLimit, nil)
 	e.SetContext(prepareCtx(trunk))
 	startKey, endKey := e.getStandbyQueueRange()
-	txsStandby, _ := e.loadStandbyTxs(&TxRange{
+	txsStandby, _, _ := e.loadStandbyTxs(&TxRange{
 		start: startKey,
 		end:   endKey,
 	})
@@ -300,16 +867,16 @@ type executeResult struct {
 }
 
 func executeTxs(randomTxs []*gethtypes.Transaction, trunk *store.TrunkStore) executeResult {
-	e := NewEbpTxExec(2000, 200, 30, 2000, &testcase.DumbSigner{}, log.NewNopLogger())
+	e := NewEbpTxExec(2000, 200, 30, 2000, &testcase.DumbSigner{}, nil, log.NewNopLogger())
 	e.SetContext(prepareCtx(trunk))
 	_ = prepareAccAndTx(e)
 	e.SetContext(prepareCtx(trunk))
 	for _, tx := range randomTxs {
 		e.CollectTx(tx)
 	}
-	e.Prepare(0, 0, DefaultTxGasLimit)
+	e.Prepare(0, 0, DefaultTxGasLimit, nil)
 	startKey, endKey := e.getStandbyQueueRange()
-	standbyTxs, _ := e.loadStandbyTxs(&TxRange{
+	standbyTxs, _, _ := e.loadStandbyTxs(&TxRange{
 		start: startKey,
 		end:   endKey,
 	})
@@ -340,10 +907,10 @@ func executeTxs(randomTxs []*gethtypes.Transaction, trunk *store.TrunkStore) exe
 func TestEmptyTxs(t *testing.T) {
 	trunk, root := prepareTruck()
 	defer closeTestCtx(root)
-	e := NewEbpTxExec(5, 2, 2, 10, &testcase.DumbSigner{}, log.NewNopLogger())
+	e := NewEbpTxExec(5, 2, 2, 10, &testcase.DumbSigner{}, nil, log.NewNopLogger())
 	e.SetContext(prepareCtx(trunk))
 	require.Equal(t, 0, e.CollectedTxsCount())
-	e.Prepare(0, 0, DefaultTxGasLimit)
+	e.Prepare(0, 0, DefaultTxGasLimit, nil)
 	e.SetContext(prepareCtx(trunk))
 	e.Execute(&types.BlockInfo{})
 	require.Equal(t, 0, len(e.CommittedTxs()))
@@ -353,7 +920,7 @@ func TestTxCountBiggerThanRunnerCount(t *testing.T) {
 	trunk, root := prepareTruck()
 	defer closeTestCtx(root)
 	//only 1 runner
-	e := NewEbpTxExec(5, 1, 2, 10, &testcase.DumbSigner{}, log.NewNopLogger())
+	e := NewEbpTxExec(5, 1, 2, 10, &testcase.DumbSigner{}, nil, log.NewNopLogger())
 	e.SetContext(prepareCtx(trunk))
 	//2 tx
 	txs := prepareAccAndTx(e)
@@ -362,7 +929,7 @@ func TestTxCountBiggerThanRunnerCount(t *testing.T) {
 		e.CollectTx(tx)
 	}
 	require.Equal(t, 2, e.CollectedTxsCount())
-	e.Prepare(0, 0, DefaultTxGasLimit)
+	e.Prepare(0, 0, DefaultTxGasLimit, nil)
 	e.SetContext(prepareCtx(trunk))
 	e.Execute(&types.BlockInfo{})
 	require.Equal(t, 2, len(e.CommittedTxs()))
@@ -373,7 +940,7 @@ func TestAccBalanceNotEnough(t *testing.T) {
 	trunk, root := prepareTruck()
 	defer closeTestCtx(root)
 	//only 1 runner
-	e := NewEbpTxExec(5, 5, 2, 10, &testcase.DumbSigner{}, log.NewNopLogger())
+	e := NewEbpTxExec(5, 5, 2, 10, &testcase.DumbSigner{}, nil, log.NewNopLogger())
 	e.SetContext(prepareCtx(trunk))
 	//2 tx
 	txs := prepareAccAndTx(e)
@@ -384,7 +951,7 @@ func TestAccBalanceNotEnough(t *testing.T) {
 	tx, _ := gethtypes.NewTransaction(1, to1, big.NewInt(20000_0000_0000), 100000, big.NewInt(1), nil).WithSignature(e.signer, from1.Bytes())
 	e.CollectTx(tx)
 	require.Equal(t, 3, e.CollectedTxsCount())
-	e.Prepare(0, 0, DefaultTxGasLimit)
+	e.Prepare(0, 0, DefaultTxGasLimit, nil)
 	e.SetContext(prepareCtx(trunk))
 	e.Execute(&types.BlockInfo{})
 	toAcc1 := e.cleanCtx.GetAccount(to1)
@@ -400,7 +967,7 @@ func TestContractCreation(t *testing.T) {
 	trunk, root := prepareTruck()
 	defer closeTestCtx(root)
 	//only 1 runner
-	e := NewEbpTxExec(5, 5, 2, 10, &testcase.DumbSigner{}, log.NewNopLogger())
+	e := NewEbpTxExec(5, 5, 2, 10, &testcase.DumbSigner{}, nil, log.NewNopLogger())
 	e.SetContext(prepareCtx(trunk))
 	prepareAccAndTx(e)
 	creationBytecode := hexToBytes(`
@@ -416,7 +983,7 @@ bc221a1460375780636299a6ef146053575b600080fd5b603d607e565b604051
 	tx, _ := gethtypes.NewContractCreation(0, big.NewInt(0), 100000, big.NewInt(1), creationBytecode).WithSignature(e.signer, from1.Bytes())
 	e.CollectTx(tx)
 	e.SetContext(prepareCtx(trunk))
-	e.Prepare(0, 0, DefaultTxGasLimit)
+	e.Prepare(0, 0, DefaultTxGasLimit, nil)
 	e.SetContext(prepareCtx(trunk))
 	e.Execute(&types.BlockInfo{})
 	require.Equal(t, 1, len(e.committedTxs))
@@ -424,10 +991,234 @@ bc221a1460375780636299a6ef146053575b600080fd5b603d607e565b604051
 	require.True(t, bytes.Equal(contractAddr[:], e.committedTxs[0].ContractAddress[:]))
 }
 
+// The runtime part of the creation bytecode in TestContractCreation. counter() (0x61bc221a)
+// returns the value in slot 0, and update(uint256) (0x6299a6ef) adds its argument to it.
+var counterRuntimeBytecode = hexToBytes(`
+6080604052348015600f57600080fd5b506004361060325760003560e01c8063
+61bc221a1460375780636299a6ef146053575b600080fd5b603d607e565b6040
+518082815260200191505060405180910390f35b607c60048036036020811015
+606757600080fd5b81019080803590602001909291905050506084565b005b60
+005481565b8060008082825401925050819055505056fea26469706673582212
+2037865cfcfd438966956583c78d31220c05c0f1ebfd116aced883214fcb1096
+c664736f6c634300060c0033
+`)
+
+// newCounterRunner returns a runner for RPC which calls counter() of to1 in 'ctx', where to1
+// is overridden to run counterRuntimeBytecode with 7 in slot 0
+func newCounterRunner(t *testing.T, ctx *types.Context) *TxRunner {
+	tx := &types.TxToRun{BasicTx: types.BasicTx{From: from1, To: to1, Gas: 100000, Data: hexToBytes("61bc221a")}}
+	runner := NewTxRunner(ctx, tx)
+	runner.ForRpc = true
+	require.NoError(t, runner.SetStateOverride(StateOverride{to1: {Code: &counterRuntimeBytecode,
+		State: map[common.Hash]common.Hash{{}: common.BigToHash(big.NewInt(7))}}}))
+	return runner
+}
+
+// newRpcRunnerWithOverride returns an executor over a fresh test store, and a runner
+// from newCounterRunner in a clean context. They are closed when the test finishes.
+func newRpcRunnerWithOverride(t *testing.T) (*txEngine, *TxRunner) {
+	trunk, root := prepareTruck()
+	t.Cleanup(func() { closeTestCtx(root) })
+	e := NewEbpTxExec(5, 5, 2, 10, &testcase.DumbSigner{}, nil, log.NewNopLogger())
+	e.SetContext(prepareCtx(trunk))
+	ctx := prepareCtx(trunk)
+	t.Cleanup(func() { ctx.Close(false) })
+	return e, newCounterRunner(t, ctx)
+}
+
+func TestRunTxForRpcWithStateOverride(t *testing.T) {
+	e, runner := newRpcRunnerWithOverride(t)
+	nonce := uint64(3)
+	overrides := StateOverride{
+		to1: {
+			Nonce: &nonce,
+			Code:  &counterRuntimeBytecode,
+			State: map[common.Hash]common.Hash{{}: common.BigToHash(big.NewInt(7))},
+		},
+	}
+	require.NoError(t, runner.SetStateOverride(overrides))
+	e.RunTxForRpc(&types.BlockInfo{}, false, runner)
+	require.Equal(t, "success", StatusToStr(runner.Status))
+	require.Equal(t, common.BigToHash(big.NewInt(7)).Bytes(), runner.OutData)
+	// the underlying store is untouched
+	require.Nil(t, e.cleanCtx.GetAccount(to1))
+	require.Nil(t, e.cleanCtx.GetCode(to1))
+
+	tx := runner.Tx
+	overrides[to1] = OverrideAccount{State: map[common.Hash]common.Hash{}, StateDiff: map[common.Hash]common.Hash{}}
+	require.Equal(t, ErrStateAndStateDiff, NewTxRunner(runner.Ctx, tx).SetStateOverride(overrides))
+
+	// the second call sees the value written by the first one, instead of the overridden one
+	overrides[to1] = OverrideAccount{Code: &counterRuntimeBytecode, State: map[common.Hash]common.Hash{{}: common.BigToHash(big.NewInt(7))}}
+	update := &types.TxToRun{BasicTx: types.BasicTx{From: from1, To: to1, Gas: 100000,
+		Data: append(hexToBytes("6299a6ef"), common.BigToHash(big.NewInt(5)).Bytes()...)}}
+	ctx := e.cleanCtx.WithRbtCopy()
+	results, err := e.RunTxsForRpc(context.Background(), ctx, &types.BlockInfo{}, []*types.TxToRun{update, tx}, overrides)
+	ctx.Close(false)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(results))
+	require.Equal(t, "success", StatusToStr(results[0].Status))
+	require.True(t, results[0].GasUsed > 21000)
+	require.Equal(t, common.BigToHash(big.NewInt(12)).Bytes(), results[1].OutData)
+}
+
+func TestStructLogger(t *testing.T) {
+	e, runner := newRpcRunnerWithOverride(t)
+	logger := &StructLogger{}
+	runner.Tracer = logger
+	e.RunTxForRpc(&types.BlockInfo{}, false, runner)
+	require.Equal(t, "success", StatusToStr(runner.Status))
+	logs := logger.StructLogs()
+	require.Equal(t, "PUSH1", logs[0].Op.String())
+	require.Equal(t, uint64(3), logs[0].GasCost)
+	require.Equal(t, 1, logs[0].Depth)
+	require.Equal(t, 0, len(logs[0].Stack))
+	require.Equal(t, uint64(0x80), logs[1].Stack[0].Uint64())
+	require.Equal(t, "RETURN", logs[len(logs)-1].Op.String())
+	sloads := 0
+	for _, l := range logs {
+		if l.Op.String() == "SLOAD" {
+			sloads++
+			require.Equal(t, map[common.Hash]common.Hash{{}: common.BigToHash(big.NewInt(7))}, l.Storage)
+		}
+	}
+	require.Equal(t, 1, sloads)
+	require.Equal(t, len(logs), len(FormatLogs(logs)))
+}
+
+func TestGasProfiler(t *testing.T) {
+	e, runner := newRpcRunnerWithOverride(t)
+	profile := NewGasProfile()
+	for i := 0; i < 2; i++ {
+		if i != 0 {
+			runner = newCounterRunner(t, runner.Ctx)
+		}
+		require.NoError(t, e.ProfileTxForRpc(context.Background(), &types.BlockInfo{}, runner, profile))
+		require.Equal(t, "success", StatusToStr(runner.Status))
+	}
+	require.Equal(t, uint64(2), profile.TxCount)
+	sload := profile.Opcodes[OpcodeKey{Contract: to1, Op: vm.SLOAD}]
+	require.Equal(t, uint64(2), sload.Count)
+	require.Equal(t, uint64(2*800), sload.Gas)
+	counter := profile.Functions[FunctionKey{Contract: to1, Selector: [4]byte{0x61, 0xbc, 0x22, 0x1a}}]
+	require.Equal(t, uint64(2), counter.Count)
+	opcodeGas := uint64(0)
+	for key, stat := range profile.Opcodes {
+		require.Equal(t, to1, key.Contract)
+		opcodeGas += stat.Gas
+	}
+	require.Equal(t, counter.Gas, opcodeGas) // no sub calls
+
+	var buf bytes.Buffer
+	require.NoError(t, profile.WritePprof(&buf))
+	require.Equal(t, []byte{0x1f, 0x8b}, buf.Bytes()[:2]) // gzip
+}
+
+func TestBerlinAccessList(t *testing.T) {
+	e, base := newRpcRunnerWithOverride(t)
+	run := func(forks *types.ForkSchedule, accessList gethtypes.AccessList) uint64 {
+		ctx := base.Ctx.WithRbtCopy()
+		defer ctx.Close(false)
+		ctx.SetForkSchedule(forks)
+		runner := newCounterRunner(t, ctx)
+		runner.Tx.AccessList = accessList
+		e.RunTxForRpc(&types.BlockInfo{}, false, runner)
+		require.Equal(t, "success", StatusToStr(runner.Status))
+		return runner.GasUsed
+	}
+	accessList := gethtypes.AccessList{{Address: to1, StorageKeys: []common.Hash{{}}}}
+	berlin := types.NewForkSchedule().Set(types.BerlinFork, 0)
+	istanbulGas := run(nil, nil)
+	require.Equal(t, istanbulGas, run(nil, accessList)) // ignored before Berlin
+	// the only SLOAD is cold: 2100 instead of 800
+	berlinGas := run(berlin, nil)
+	require.Equal(t, istanbulGas+2100-800, berlinGas)
+	// the slot is warm, and the access list costs 2400+1900
+	require.Equal(t, berlinGas-2100+100+2400+1900, run(berlin, accessList))
+}
+
+func TestCreateAccessList(t *testing.T) {
+	e, runner := newRpcRunnerWithOverride(t)
+	accessList, gasUsed, err := e.CreateAccessList(context.Background(), &types.BlockInfo{}, runner)
+	require.NoError(t, err)
+	require.Equal(t, "success", StatusToStr(runner.Status))
+	require.True(t, gasUsed > 21000)
+	require.Equal(t, runner.GasUsed, gasUsed)
+	// the recipient is listed because its storage is touched
+	require.Equal(t, gethtypes.AccessList{{Address: to1, StorageKeys: []common.Hash{{}}}}, accessList)
+	require.Nil(t, runner.Tx.AccessList)
+
+	// after Berlin, the gas is that of the TX carrying the list
+	runner.Ctx.SetForkSchedule(types.NewForkSchedule().Set(types.BerlinFork, 0))
+	accessList, gasUsed, err = e.CreateAccessList(context.Background(), &types.BlockInfo{}, runner)
+	require.NoError(t, err)
+	require.Equal(t, gethtypes.AccessList{{Address: to1, StorageKeys: []common.Hash{{}}}}, accessList)
+	run := func(accessList gethtypes.AccessList) uint64 {
+		ctx := runner.Ctx.WithRbtCopy()
+		defer ctx.Close(false)
+		withList := newCounterRunner(t, ctx)
+		withList.Tx.AccessList = accessList
+		e.RunTxForRpc(&types.BlockInfo{}, false, withList)
+		require.Equal(t, "success", StatusToStr(withList.Status))
+		return withList.GasUsed
+	}
+	require.Equal(t, run(accessList), gasUsed)
+	require.Equal(t, run(nil)+2400+1900+100-2100, gasUsed)
+}
+
+func TestCreateAccessListWithOverriddenCode(t *testing.T) {
+	e, runner := newRpcRunnerWithOverride(t)
+	extCodeSize := hexToBytes("60203b00") // PUSH1 0x20 EXTCODESIZE STOP, where 0x20 is to2
+	require.NoError(t, runner.SetStateOverride(StateOverride{
+		to1: {Code: &extCodeSize},
+		to2: {Code: &counterRuntimeBytecode},
+	}))
+	accessList, _, err := e.CreateAccessList(context.Background(), &types.BlockInfo{}, runner)
+	require.NoError(t, err)
+	require.Equal(t, "success", StatusToStr(runner.Status))
+	require.Equal(t, gethtypes.AccessList{{Address: to2, StorageKeys: []common.Hash{}}}, accessList)
+	// the overridden bytecodes are recorded like the ones read from the store
+	codes := make(map[common.Address][]byte)
+	for _, op := range runner.RwLists.BytecodeRList {
+		codes[op.Addr] = types.NewBytecodeInfo(op.Bytecode).BytecodeSlice()
+	}
+	require.Equal(t, map[common.Address][]byte{to1: extCodeSize, to2: counterRuntimeBytecode}, codes)
+}
+
+func TestEstimateGas(t *testing.T) {
+	e, counter := newRpcRunnerWithOverride(t)
+	ctx := counter.Ctx
+	overrides := StateOverride{to1: {Code: &counterRuntimeBytecode}}
+	update := &types.TxToRun{BasicTx: types.BasicTx{From: from1, To: to1,
+		Data: append(hexToBytes("6299a6ef"), common.BigToHash(big.NewInt(5)).Bytes()...)}}
+	gas, err := e.EstimateGas(context.Background(), ctx, &types.BlockInfo{}, update, 0, overrides)
+	require.NoError(t, err)
+	require.True(t, gas > intrinsicGas(update.Data, false) && gas < DefaultTxGasLimit)
+	tx := *update
+	tx.Gas = gas
+	runner := NewTxRunner(ctx.WithRbtCopy(), &tx)
+	runner.ForRpc = true
+	require.NoError(t, runner.SetStateOverride(overrides))
+	e.RunTxForRpc(&types.BlockInfo{}, false, runner)
+	require.Equal(t, "success", StatusToStr(runner.Status))
+	runner.Ctx.Close(false)
+
+	// an unknown selector always reverts
+	update.Data = hexToBytes("12345678")
+	_, err = e.EstimateGas(context.Background(), ctx, &types.BlockInfo{}, update, 0, overrides)
+	require.Equal(t, "revert", StatusToStr(err.(*EstimateGasError).Status))
+
+	// an explicit gas limit is not replaced by the cap, even if it is below the intrinsic gas
+	update.Gas = intrinsicGas(update.Data, false) - 1
+	_, err = e.EstimateGas(context.Background(), ctx, &types.BlockInfo{}, update, 0, overrides)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "intrinsic gas too low")
+}
+
 func TestRandomPrepare(t *testing.T) {
 	trunk, root := prepareTruck()
 	defer closeTestCtx(root)
-	e := NewEbpTxExec(5, 5, 5, 10, &testcase.DumbSigner{}, log.NewNopLogger())
+	e := NewEbpTxExec(5, 5, 5, 10, &testcase.DumbSigner{}, nil, log.NewNopLogger())
 	e.SetContext(prepareCtx(trunk))
 	txs := prepareAccAndTx(e)
 	e.CollectTx(txs[0])
@@ -458,7 +1249,7 @@ func TestRandomPrepare(t *testing.T) {
 		e.CollectTx(tx9)
 		e.CollectTx(tx10)
 		e.CollectTx(tx11)
-		e.Prepare(0, 0, DefaultTxGasLimit)
+		e.Prepare(0, 0, DefaultTxGasLimit, nil)
 		require.Equal(t, 12*i+12, e.StandbyQLen())
 	}
 }
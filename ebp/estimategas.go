@@ -0,0 +1,140 @@
+package ebp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/smartbch/moeingevm/types"
+)
+
+// The same as TX_GAS, TX_GAS_CONTRACT_CREATION, TX_DATA_ZERO_GAS, TX_DATA_NON_ZERO_GAS,
+// TX_ACCESS_LIST_ADDRESS_GAS, TX_ACCESS_LIST_STORAGE_KEY_GAS and INITCODE_WORD_GAS in tx_ctrl.h
+const (
+	txGas                     uint64 = 21000
+	txGasContractCreation     uint64 = 53000
+	txDataZeroGas             uint64 = 4
+	txDataNonZeroGas          uint64 = 16
+	txAccessListAddressGas    uint64 = 2400
+	txAccessListStorageKeyGas uint64 = 1900
+	initCodeWordGas           uint64 = 2
+)
+
+// Returned by EstimateGas when the TX cannot succeed even with the gas cap
+type EstimateGasError struct {
+	Status  int
+	OutData []byte
+	Reason  string // the decoded revert reason, see types.DecodeRevertReason
+}
+
+func (e *EstimateGasError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("execution reverted: %s", e.Reason)
+	}
+	return fmt.Sprintf("gas required exceeds allowance or always failing transaction (%s)", StatusToStr(e.Status))
+}
+
+// The gas consumed before starting EVM, the same as intrinsic_gas in host_context.cpp
+func intrinsicGas(data []byte, isContractCreation bool) uint64 {
+	gas := txGas
+	if isContractCreation {
+		gas = txGasContractCreation
+	}
+	for _, b := range data {
+		if b == 0 {
+			gas += txDataZeroGas
+		} else {
+			gas += txDataNonZeroGas
+		}
+	}
+	return gas
+}
+
+// The intrinsic gas of an access list after the Berlin fork, the same as access_list_gas in host_context.cpp
+func accessListGas(list gethtypes.AccessList) uint64 {
+	gas := uint64(0)
+	for _, tuple := range list {
+		gas += txAccessListAddressGas + uint64(len(tuple.StorageKeys))*txAccessListStorageKeyGas
+	}
+	return gas
+}
+
+// Estimate the gas limit with which 'tx' can succeed. The estimation of zero_depth_call is used first,
+// and it is validated by re-execution. If it is missing or insufficient, we binary-search between the
+// intrinsic gas and the cap, which is tx.Gas if it is set, or 'gasCap' (DefaultTxGasLimit if zero).
+// Each execution uses a fresh copy of 'rpcCtx', whose rabbit store must be clean, with 'overrides'
+// applied. If the TX fails with the cap, an *EstimateGasError is returned.
+func (exec *txEngine) EstimateGas(ctx context.Context, rpcCtx *types.Context, currBlock *types.BlockInfo,
+	tx *types.TxToRun, gasCap uint64, overrides StateOverride) (uint64, error) {
+	if gasCap == 0 {
+		gasCap = DefaultTxGasLimit
+	}
+	isContractCreation := tx.To == common.Address{}
+	lo := intrinsicGas(tx.Data, isContractCreation) - 1
+	if rpcCtx.IsForkActive(types.BerlinFork) {
+		lo += accessListGas(tx.AccessList)
+	}
+	if isContractCreation && rpcCtx.IsForkActive(types.ShanghaiFork) { // EIP-3860
+		lo += (uint64(len(tx.Data)) + 31) / 32 * initCodeWordGas
+	}
+	hi := gasCap
+	if tx.Gas != 0 {
+		hi = tx.Gas
+	}
+	if hi <= lo {
+		return 0, fmt.Errorf("intrinsic gas too low: have %d, want %d", hi, lo+1)
+	}
+
+	// run a copy of the TX with 'gas' and return its runner
+	run := func(gas uint64, estimateGas bool) (runner *TxRunner, estimated int64, err error) {
+		txCopy := *tx
+		txCopy.Gas = gas
+		runner = NewTxRunner(rpcCtx.WithRbtCopy(), &txCopy)
+		defer runner.Ctx.Close(false)
+		runner.ForRpc = true
+		if len(overrides) != 0 {
+			if runner.overrides, err = newStateOverrideLayer(overrides); err != nil {
+				return nil, 0, err
+			}
+		}
+		estimated, err = exec.runnerTable.runTxForRpcWithContext(ctx, currBlock, estimateGas, runner)
+		return
+	}
+
+	runner, estimated, err := run(hi, true)
+	if err != nil {
+		return 0, err
+	}
+	if StatusIsFailure(runner.Status) {
+		reason := ""
+		if StatusIsRevert(runner.Status) {
+			reason = types.DecodeRevertReason(runner.OutData, nil)
+		}
+		return 0, &EstimateGasError{Status: runner.Status, OutData: runner.OutData, Reason: reason}
+	}
+	if estimated > 0 && uint64(estimated) < hi {
+		runner, _, err = run(uint64(estimated), false)
+		if err != nil {
+			return 0, err
+		}
+		if !StatusIsFailure(runner.Status) {
+			return uint64(estimated), nil
+		}
+		lo = uint64(estimated) // the estimation is insufficient
+	}
+	for lo+1 < hi {
+		mid := (lo + hi) / 2
+		runner, _, err = run(mid, false)
+		if err != nil {
+			return 0, err
+		}
+		if StatusIsFailure(runner.Status) {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return hi, nil
+}
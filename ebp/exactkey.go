@@ -0,0 +1,236 @@
+package ebp
+
+import (
+	"encoding/binary"
+
+	"github.com/holiman/uint256"
+
+	"github.com/smartbch/moeingevm/types"
+)
+
+// By default, commitNonConflictingRunners detects conflicts on the short keys of rabbit stores. A rabbit
+// store touches extra short keys when it jumps over occupied slots, so two TXs accessing different KV pairs
+// may be taken as conflicting. In the exact-key mode, the runners record the original keys they read and
+// write, and a TX conflicts with an earlier committed TX in the same round only if:
+//   1. it writes a short key written by the earlier TX, which must be avoided to write back rabbit stores
+//      safely, no matter whether the original keys are the same; or
+//   2. it reads or writes an original key written by the earlier TX, subject to the policy of the key's class.
+// The TXs calling system contracts directly cannot be tracked with original keys, so they are checked with
+// short keys as before, and so are the reads of all the TXs following them in the same round.
+// The read/write lists recorded in rwListMap still contain short keys, because they are only hints for loading.
+
+// How a class of original keys takes part in the conflict detection of exact-key mode
+type KeyConflictPolicy uint8
+
+const (
+	// Reading or writing a key written by an earlier TX in the same round is a conflict
+	ConflictOnReadWrite KeyConflictPolicy = iota
+	// Only writing a key written by an earlier TX in the same round is a conflict, the TX may read a stale value
+	ConflictOnWrite
+)
+
+type ExactKeyConflictConfig struct {
+	Account         KeyConflictPolicy
+	Bytecode        KeyConflictPolicy
+	Storage         KeyConflictPolicy
+	CreationCounter KeyConflictPolicy
+	// Merge the balance changes made to the system account by different TXs, instead of taking them as
+	// conflicts. It only works for the TXs which do not change its nonce and sequence, and the TXs must not
+	// depend on the balance they read from the system account.
+	CommutativeSystemBalance bool
+}
+
+type keyClass uint8
+
+const (
+	accountKeyClass keyClass = iota
+	bytecodeKeyClass
+	storageKeyClass
+	creationCounterKeyClass
+)
+
+// An original key accessed by a runner
+type accessedKey struct {
+	key     string
+	class   keyClass
+	isWrite bool
+}
+
+// Record an original key if the runner tracks them
+func (runner *TxRunner) recordKey(class keyClass, key []byte, isWrite bool) {
+	if runner.trackKeys {
+		runner.accessedKeys = append(runner.accessedKeys, accessedKey{key: string(key), class: class, isWrite: isWrite})
+	}
+}
+
+var systemAccountKey = string(types.GetAccountKey(systemContractAddress))
+
+// exactKeyChecker detects conflicts among the runners of one round in exact-key mode. Its state is
+// updated in the same order as the runners are committed, so the result is deterministic.
+type exactKeyChecker struct {
+	cfg          *ExactKeyConflictConfig
+	writtenKeys  map[string]struct{}
+	sawUntracked bool // a runner which does not track original keys has been committed
+	// the system account before this round, and its short key in rabbit store
+	sysAcc      *types.AccountInfo
+	sysShortKey uint64
+	sysAdded    *uint256.Int
+	sysSubbed   *uint256.Int
+	sysMerged   bool
+	sysLocked   bool // a committed runner changed the system account without merging
+}
+
+func (exec *txEngine) newExactKeyChecker() *exactKeyChecker {
+	checker := &exactKeyChecker{
+		cfg:         exec.exactKeyConfig,
+		writtenKeys: make(map[string]struct{}, 1024),
+		sysAdded:    uint256.NewInt(0),
+		sysSubbed:   uint256.NewInt(0),
+	}
+	if checker.cfg.CommutativeSystemBalance {
+		ctx := exec.cleanCtx.WithRbtCopy()
+		checker.sysAcc = ctx.GetAccount(systemContractAddress)
+		if path, ok := ctx.Rbt.GetShortKeyPath([]byte(systemAccountKey)); ok {
+			checker.sysShortKey = binary.LittleEndian.Uint64(path[len(path)-1][:])
+		} else {
+			checker.sysAcc = nil // no balance to merge
+		}
+		ctx.Close(false)
+	}
+	return checker
+}
+
+func (c *exactKeyChecker) policy(class keyClass) KeyConflictPolicy {
+	switch class {
+	case accountKeyClass:
+		return c.cfg.Account
+	case bytecodeKeyClass:
+		return c.cfg.Bytecode
+	case storageKeyClass:
+		return c.cfg.Storage
+	default:
+		return c.cfg.CreationCounter
+	}
+}
+
+// Decide whether the runner's change to the system account can be merged. It returns the balance change
+// if so. The system account must only change its balance, and its short key must not be passed by the
+// rabbit jumps of the keys written by this runner.
+func (c *exactKeyChecker) systemBalanceDelta(runner *TxRunner) (delta *uint256.Int, isAdd, ok bool) {
+	if c.sysAcc == nil || c.sysLocked || !runner.trackKeys {
+		return nil, false, false
+	}
+	written := false
+	for _, ak := range runner.accessedKeys {
+		if !ak.isWrite {
+			continue
+		}
+		if ak.key == systemAccountKey {
+			written = true
+		} else if path, _ := runner.Ctx.Rbt.GetShortKeyPath([]byte(ak.key)); len(path) > 1 {
+			for _, k := range path[:len(path)-1] {
+				if binary.LittleEndian.Uint64(k[:]) == c.sysShortKey {
+					return nil, false, false
+				}
+			}
+		}
+	}
+	if !written {
+		return nil, false, false
+	}
+	acc := runner.Ctx.GetAccount(systemContractAddress)
+	if acc == nil || acc.Nonce() != c.sysAcc.Nonce() || acc.Sequence() != c.sysAcc.Sequence() {
+		return nil, false, false
+	}
+	oldBalance, newBalance := c.sysAcc.Balance(), acc.Balance()
+	if newBalance.Cmp(oldBalance) >= 0 {
+		return newBalance.Sub(newBalance, oldBalance), true, true
+	}
+	delta = oldBalance.Sub(oldBalance, newBalance)
+	// the merged balance must not be negative
+	merged := uint256.NewInt(0).Add(c.sysAcc.Balance(), c.sysAdded)
+	if merged.Lt(uint256.NewInt(0).Add(c.sysSubbed, delta)) {
+		return nil, false, false
+	}
+	return delta, false, true
+}
+
+// Whether a short key touched by the runner must be checked against the short keys written by committed runners
+func (c *exactKeyChecker) checksShortKey(runner *TxRunner, k uint64, dirty, mergeSys bool) bool {
+	if mergeSys && k == c.sysShortKey {
+		return false
+	}
+	return dirty || !runner.trackKeys || c.sawUntracked
+}
+
+// Return the first original key of the runner which conflicts with the committed runners, or nil
+func (c *exactKeyChecker) conflictingKey(runner *TxRunner, mergeSys bool) []byte {
+	if !runner.trackKeys {
+		return nil
+	}
+	for _, ak := range runner.accessedKeys {
+		if mergeSys && ak.key == systemAccountKey {
+			continue
+		}
+		if !ak.isWrite && c.policy(ak.class) != ConflictOnReadWrite {
+			continue
+		}
+		if _, ok := c.writtenKeys[ak.key]; ok {
+			return []byte(ak.key)
+		}
+	}
+	return nil
+}
+
+// Record the committed runner, after 'touchedSet' has been updated with its written short keys
+func (c *exactKeyChecker) commit(runner *TxRunner, touchedSet map[uint64]struct{}, sysDelta *uint256.Int, isAdd, mergeSys bool) {
+	if _, ok := touchedSet[c.sysShortKey]; ok && !mergeSys && c.sysAcc != nil {
+		c.sysLocked = true // the later runners would overwrite its change if they were merged
+	}
+	if !runner.trackKeys {
+		c.sawUntracked = true
+		return
+	}
+	for _, ak := range runner.accessedKeys {
+		if ak.isWrite && !(mergeSys && ak.key == systemAccountKey) {
+			c.writtenKeys[ak.key] = struct{}{}
+		}
+	}
+	if mergeSys {
+		c.sysMerged = true
+		if isAdd {
+			c.sysAdded.Add(c.sysAdded, sysDelta)
+		} else {
+			c.sysSubbed.Add(c.sysSubbed, sysDelta)
+		}
+	}
+}
+
+// Overwrite the system account written back by the last runner with the merged balance
+func (c *exactKeyChecker) writeMergedSystemBalance(exec *txEngine) {
+	if !c.sysMerged {
+		return
+	}
+	ctx := exec.cleanCtx.WithRbtCopy()
+	acc := ctx.GetAccount(systemContractAddress)
+	balance := c.sysAcc.Balance()
+	balance.Add(balance, c.sysAdded)
+	balance.Sub(balance, c.sysSubbed)
+	acc.UpdateBalance(balance)
+	ctx.SetAccount(systemContractAddress, acc)
+	ctx.Close(true)
+}
+
+// Enable the exact-key mode with 'cfg', or disable it with nil
+func (exec *txEngine) SetExactKeyConflict(cfg *ExactKeyConflictConfig) {
+	exec.exactKeyConfig = cfg
+}
+
+func (exec *txEngine) newTxRunner(tx *types.TxToRun) *TxRunner {
+	runner := NewTxRunner(exec.cleanCtx.WithRbtCopy(), tx)
+	runner.trackKeys = exec.exactKeyConfig != nil
+	if exec.gasProfile != nil {
+		runner.Tracer = NewGasProfiler(NewGasProfile())
+	}
+	return runner
+}
@@ -1,6 +1,8 @@
 package ebp
 
 import (
+	"context"
+
 	"github.com/ethereum/go-ethereum/common"
 	gethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/holiman/uint256"
@@ -12,14 +14,31 @@ import (
 type TxExecutor interface {
 	SetAotParam(aotDir string, aotReloadInterval int64)
 	SetCheckRWInLoading(b bool)
+	SetDAGScheduling(b bool)
+	SetExactKeyConflict(cfg *ExactKeyConflictConfig)
+	RegisterPredefinedContract(ctx *types.Context, address common.Address, executor types.SystemContractExecutor)
+	RunTxForRpc(currBlock *types.BlockInfo, estimateGas bool, runner *TxRunner) int64
+	RunTxForRpcWithContext(ctx context.Context, currBlock *types.BlockInfo, estimateGas bool, runner *TxRunner) (int64, error)
+	SetRpcLimits(queueLimit int, stepBudget int64)
+	RunTxsForRpc(ctx context.Context, rpcCtx *types.Context, currBlock *types.BlockInfo, txs []*types.TxToRun,
+		overrides StateOverride) ([]RpcCallResult, error)
+	CreateAccessList(ctx context.Context, currBlock *types.BlockInfo, runner *TxRunner) (gethtypes.AccessList, uint64, error)
+	EstimateGas(ctx context.Context, rpcCtx *types.Context, currBlock *types.BlockInfo, tx *types.TxToRun, gasCap uint64,
+		overrides StateOverride) (uint64, error)
+	ProfileTxForRpc(ctx context.Context, currBlock *types.BlockInfo, runner *TxRunner, profile *GasProfile) error
+	SetGasProfile(profile *GasProfile)
+	Close()
 
 	//step 1: for deliverTx, collect block txs in engine.txList
 	CollectTx(tx *gethtypes.Transaction)
-	//step 2: for commit, check sig, insert regular txs standbyTxQ
-	Prepare(reorderSeed int64, minGasPrice, maxTxGasLimit uint64) Frontier
+	//step 2: for commit, check sig, insert regular txs standbyTxQ. baseFee is the pending block's (nil means zero)
+	Prepare(reorderSeed int64, minGasPrice, maxTxGasLimit uint64, baseFee *uint256.Int) Frontier
 	//step 3: for postCommit, parallel execute tx in standbyTxQ
 	Execute(currBlock *types.BlockInfo)
 
+	//run Prepare and Execute on a throw-away copy of world state
+	SimulateBlock(txs []*gethtypes.Transaction, blockInfo *types.BlockInfo, reorderSeed int64) *SimulationResult
+
 	//set context
 	SetContext(ctx *types.Context)
 	Context() *types.Context
@@ -29,8 +48,17 @@ type TxExecutor interface {
 	CommittedTxs() []*types.Transaction
 	CommittedTxIds() [][32]byte
 	CommittedTxsForMoDB() []modbtypes.Tx
-	GasUsedInfo() (gasUsed uint64, feeRefund, gasFee uint256.Int)
+	//gasFee goes to the validators, it does not include burntFee, which is burnt according to EIP-1559.
+	//So gasFee+burntFee is what the senders paid.
+	GasUsedInfo() (gasUsed uint64, feeRefund, gasFee, burntFee uint256.Int)
 	StandbyQLen() int
+	ExecutionReport() *ExecutionReport
+
+	//inspect and manage standby queue, not thread safe
+	GetStandbyTxs(height int64, offset, limit int) []StandbyTx
+	GetStandbyTxByHash(height int64, txHash common.Hash) (StandbyTx, bool)
+	GetStandbyTxsBySender(height int64, sender common.Address) []StandbyTx
+	PurgeStandbyQ() int
 }
 
 type Frontier interface {
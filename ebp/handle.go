@@ -0,0 +1,144 @@
+package ebp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/smartbch/moeingevm/types"
+)
+
+// The parameter 'collector_handler' passed to zero_depth_call_wrap selects one TxRunner. Its high bits
+// select a runnerTable from the registry below and its low bits select a TxRunner in the table: a value
+// less than RpcRunnersIdStart selects a runner for transactions in block, and the others select an RPC runner.
+const (
+	handlerIdxBits  = 20
+	handlerIdxMask  = (1 << handlerIdxBits) - 1
+	MaxRunnerTables = 1024
+
+	// How many callers of RunTxForRpcWithContext can wait for idle RPC runners by default
+	DefaultRpcQueueLimit = 4 * RpcRunnersCount
+)
+
+var ErrRpcQueueFull = errors.New("too many RPC calls are waiting")
+
+var (
+	runnerTableRegistry     [MaxRunnerTables]*runnerTable
+	runnerTableRegistryLock sync.RWMutex // getRunnerTable is called from C on hot paths, so it only takes the read lock
+)
+
+// Each txEngine owns a runnerTable, so several engines in one process do not interfere with each other
+type runnerTable struct {
+	id int
+	// For transactions in block
+	runners []*TxRunner
+	// For transactions in Web3 RPC: call and estimateGas
+	rpcRunners [RpcRunnersCount]*TxRunner
+	// The indexes of idle RPC runners. The goroutines blocked on receiving from a channel are woken up
+	// in FIFO order, so the callers waiting for idle runners are served fairly.
+	freeRpcRunners chan int
+	// How many callers are waiting for idle RPC runners, and the limit for RunTxForRpcWithContext
+	rpcWaiting    int32
+	rpcQueueLimit int32
+	// How many basic blocks one RunTxForRpcWithContext can execute, negative means no limit
+	rpcStepBudget int64
+	// The system contracts which can be called by the runners
+	predefinedContracts map[common.Address]types.SystemContractExecutor
+}
+
+// Create a runnerTable and register it, it panics when all the MaxRunnerTables slots are taken
+func newRunnerTable(runnerNumber int) *runnerTable {
+	if runnerNumber > RpcRunnersIdStart {
+		panic(fmt.Sprintf("runnerNumber %d exceeds %d", runnerNumber, RpcRunnersIdStart))
+	}
+	t := &runnerTable{
+		runners:             make([]*TxRunner, runnerNumber),
+		freeRpcRunners:      make(chan int, RpcRunnersCount),
+		rpcQueueLimit:       int32(DefaultRpcQueueLimit),
+		rpcStepBudget:       -1,
+		predefinedContracts: make(map[common.Address]types.SystemContractExecutor),
+	}
+	for i := 0; i < RpcRunnersCount; i++ {
+		t.freeRpcRunners <- i
+	}
+	runnerTableRegistryLock.Lock()
+	defer runnerTableRegistryLock.Unlock()
+	for id := range runnerTableRegistry {
+		if runnerTableRegistry[id] == nil {
+			t.id = id
+			runnerTableRegistry[id] = t
+			return t
+		}
+	}
+	panic("Too many runner tables")
+}
+
+// Remove the runnerTable from the registry, after which its handlers become invalid
+func (t *runnerTable) release() {
+	runnerTableRegistryLock.Lock()
+	defer runnerTableRegistryLock.Unlock()
+	if runnerTableRegistry[t.id] == t {
+		runnerTableRegistry[t.id] = nil
+	}
+}
+
+func (t *runnerTable) handler(idx int) int {
+	return t.id<<handlerIdxBits | idx
+}
+
+func getRunnerTable(handler int) *runnerTable {
+	runnerTableRegistryLock.RLock()
+	defer runnerTableRegistryLock.RUnlock()
+	return runnerTableRegistry[handler>>handlerIdxBits]
+}
+
+func getRunner(handler int) (runner *TxRunner) {
+	t := getRunnerTable(handler)
+	i := handler & handlerIdxMask
+	if i < RpcRunnersIdStart {
+		runner = t.runners[i]
+		runner.ForRpc = false
+	} else {
+		runner = t.rpcRunners[i-RpcRunnersIdStart]
+		runner.ForRpc = true
+	}
+	return
+}
+
+func (t *runnerTable) registerPredefinedContract(ctx *types.Context, address common.Address, executor types.SystemContractExecutor) {
+	t.predefinedContracts[address] = executor
+	if !executor.IsSystemContract(address) {
+		panic(fmt.Sprintf("contract %s is not system contract", address.String()))
+	}
+	executor.Init(ctx)
+}
+
+// Wait for an idle RPC runner and return its index. If 'bounded' is true, it fails fast with ErrRpcQueueFull
+// when 'rpcQueueLimit' callers are already waiting. It returns ctx.Err() if 'ctx' is done before getting one.
+func (t *runnerTable) acquireRpcRunner(ctx context.Context, bounded bool) (int, error) {
+	select {
+	case idx := <-t.freeRpcRunners:
+		return idx, nil
+	default:
+	}
+	waiting := atomic.AddInt32(&t.rpcWaiting, 1)
+	defer atomic.AddInt32(&t.rpcWaiting, -1)
+	if bounded && waiting > atomic.LoadInt32(&t.rpcQueueLimit) {
+		return -1, ErrRpcQueueFull
+	}
+	select {
+	case idx := <-t.freeRpcRunners:
+		return idx, nil
+	case <-ctx.Done():
+		return -1, ctx.Err()
+	}
+}
+
+func (t *runnerTable) releaseRpcRunner(idx int) {
+	t.rpcRunners[idx] = nil
+	t.freeRpcRunners <- idx
+}
@@ -0,0 +1,52 @@
+package ebp
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/smartbch/moeingevm/types"
+)
+
+// The result of one call run by RunTxsForRpc
+type RpcCallResult struct {
+	Status                 int
+	OutData                []byte
+	Logs                   []types.EvmLog
+	GasUsed                uint64
+	CreatedContractAddress common.Address
+}
+
+// Run 'txs' one by one on 'rpcCtx', so each TX sees the changes made by the TXs before it, even if they
+// failed (the failed TXs still increase their senders' nonces). 'overrides' is applied before the first
+// TX, and may be nil. The changes are written to the cache of 'rpcCtx.Rbt', and the caller must close
+// 'rpcCtx' without writing them back. If 'ctx' is done or the step budget is exhausted, the results of
+// the finished TXs are returned with the error.
+func (exec *txEngine) RunTxsForRpc(ctx context.Context, rpcCtx *types.Context, currBlock *types.BlockInfo,
+	txs []*types.TxToRun, overrides StateOverride) ([]RpcCallResult, error) {
+	var layer *stateOverrideLayer
+	if len(overrides) != 0 {
+		var err error
+		layer, err = newStateOverrideLayer(overrides)
+		if err != nil {
+			return nil, err
+		}
+	}
+	results := make([]RpcCallResult, 0, len(txs))
+	for _, tx := range txs {
+		runner := NewTxRunner(rpcCtx, tx)
+		runner.ForRpc = true
+		runner.overrides = layer
+		if _, err := exec.runnerTable.runTxForRpcWithContext(ctx, currBlock, false, runner); err != nil {
+			return results, err
+		}
+		results = append(results, RpcCallResult{
+			Status:                 runner.Status,
+			OutData:                runner.OutData,
+			Logs:                   runner.Logs,
+			GasUsed:                runner.GasUsed,
+			CreatedContractAddress: runner.CreatedContractAddress,
+		})
+	}
+	return results, nil
+}
@@ -0,0 +1,59 @@
+package ebp
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/seehuhn/mt19937"
+
+	"github.com/smartbch/moeingevm/types"
+)
+
+// TxOrderer decides the order in which the senders' TXs are inserted into the standby queue during 'Prepare'.
+// Each group passed to OrderSenders contains all the TXs of one sender, in the order they were collected.
+// Only the order of the groups can be changed: the TXs with the same sender are always placed back-to-back,
+// because runTxInParallel relies on it. The result must only depend on the groups and the seed.
+type TxOrderer interface {
+	OrderSenders(groups [][]*types.TxToRun, reorderSeed int64)
+}
+
+var (
+	_ TxOrderer = RandomTxOrderer{}
+	_ TxOrderer = GasPriceTxOrderer{}
+	_ TxOrderer = FirstSeenTxOrderer{}
+)
+
+// RandomTxOrderer shuffles the senders with an mt19937 generator seeded by 'reorderSeed'.
+// It is the default TxOrderer.
+type RandomTxOrderer struct{}
+
+func (RandomTxOrderer) OrderSenders(groups [][]*types.TxToRun, reorderSeed int64) {
+	rand := mt19937.New()
+	rand.Seed(reorderSeed)
+	for i := 0; i < len(groups); i++ { // shuffle the senders
+		r0 := int(rand.Int63()) % len(groups)
+		r1 := int(rand.Int63()) % len(groups)
+		groups[r0], groups[r1] = groups[r1], groups[r0]
+	}
+}
+
+// GasPriceTxOrderer places the senders whose first TXs have higher gas prices ahead. The senders with
+// the same gas price are ordered by their addresses, such that the result does not depend on the
+// order in which the TXs were collected.
+type GasPriceTxOrderer struct{}
+
+func (GasPriceTxOrderer) OrderSenders(groups [][]*types.TxToRun, _ int64) {
+	sort.Slice(groups, func(i, j int) bool {
+		txI, txJ := groups[i][0], groups[j][0]
+		// GasPrice is a big-endian 256-bit integer, so it can be compared as bytes
+		if cmp := bytes.Compare(txI.GasPrice[:], txJ.GasPrice[:]); cmp != 0 {
+			return cmp > 0
+		}
+		return bytes.Compare(txI.From[:], txJ.From[:]) < 0
+	})
+}
+
+// FirstSeenTxOrderer keeps the senders in the order their first TXs were collected
+type FirstSeenTxOrderer struct{}
+
+func (FirstSeenTxOrderer) OrderSenders(_ [][]*types.TxToRun, _ int64) {}
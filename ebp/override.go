@@ -0,0 +1,187 @@
+package ebp
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+
+	"github.com/smartbch/moeingevm/types"
+)
+
+var (
+	ErrStateAndStateDiff = errors.New("state and stateDiff cannot be overridden at the same time")
+	ErrOverrideCodeSize  = errors.New("overridden code is too large")
+)
+
+// The fields to override for one account, like the stateOverride parameter of geth's eth_call.
+// A nil field keeps the original value.
+type OverrideAccount struct {
+	Nonce   *uint64
+	Code    *[]byte
+	Balance *uint256.Int
+	// Replace the whole storage of the account
+	State map[common.Hash]common.Hash
+	// Replace some storage slots of the account, the others keep their original values
+	StateDiff map[common.Hash]common.Hash
+}
+
+type StateOverride map[common.Address]OverrideAccount
+
+// The accounts created by overriding code get sequences starting from here, which are too large to be
+// allocated with creation counters
+const overrideSequenceStart = uint64(1) << 62
+
+// The state overrides of one RPC runner, layered over runner.Ctx.Rbt. It is consulted by getAccountInfo,
+// getBytecode and getValue, and the underlying store is never written. The predefined contracts read
+// runner.Ctx directly, so they do not see the overrides.
+type stateOverrideLayer struct {
+	accounts map[common.Address]*OverrideAccount
+	// Which overridden account owns the storage of a sequence, filled when the account is first read
+	seqToAddr map[uint64]common.Address
+	// The sequences allocated to the EOAs and non-existent accounts whose code is overridden
+	newSeqs map[common.Address]uint64
+	nextSeq uint64
+	// The storage slots written by the earlier calls of RunTxsForRpc, whose values are in the underlying store
+	writtenValues map[string]struct{}
+}
+
+func newStateOverrideLayer(overrides StateOverride) (*stateOverrideLayer, error) {
+	layer := &stateOverrideLayer{
+		accounts:      make(map[common.Address]*OverrideAccount, len(overrides)),
+		seqToAddr:     make(map[uint64]common.Address, len(overrides)),
+		newSeqs:       make(map[common.Address]uint64),
+		nextSeq:       overrideSequenceStart,
+		writtenValues: make(map[string]struct{}),
+	}
+	for addr, acc := range overrides {
+		if acc.State != nil && acc.StateDiff != nil {
+			return nil, ErrStateAndStateDiff
+		}
+		if acc.Code != nil && len(*acc.Code) > len(big_buffer{}.data) {
+			return nil, ErrOverrideCodeSize
+		}
+		acc := acc
+		if acc.Nonce != nil { // runTxHelper may increase the sender's nonce, so we take a copy
+			nonce := *acc.Nonce
+			acc.Nonce = &nonce
+		}
+		layer.accounts[addr] = &acc
+	}
+	return layer, nil
+}
+
+// Run the runner's TX with 'overrides' applied, which must be called before RunTxForRpc
+func (runner *TxRunner) SetStateOverride(overrides StateOverride) error {
+	layer, err := newStateOverrideLayer(overrides)
+	if err != nil {
+		return err
+	}
+	runner.overrides = layer
+	return nil
+}
+
+// Apply the overrides to 'acc' read from the underlying store, which may be nil
+func (layer *stateOverrideLayer) getAccount(addr common.Address, acc *types.AccountInfo) *types.AccountInfo {
+	o, ok := layer.accounts[addr]
+	if !ok {
+		return acc
+	}
+	if acc == nil {
+		if o.Nonce == nil && o.Balance == nil && o.Code == nil {
+			return nil
+		}
+		acc = types.ZeroAccountInfo()
+		acc.UpdateSequence(^uint64(0)) // an EOA, until code is overridden
+	} else {
+		acc = types.NewAccountInfo(append([]byte{}, acc.Bytes()...))
+	}
+	if o.Nonce != nil {
+		acc.UpdateNonce(*o.Nonce)
+	}
+	if o.Balance != nil {
+		acc.UpdateBalance(o.Balance)
+	}
+	if o.Code != nil && len(*o.Code) != 0 && acc.Sequence() == ^uint64(0) {
+		seq, ok := layer.newSeqs[addr]
+		if !ok {
+			seq = layer.nextSeq
+			layer.nextSeq++
+			layer.newSeqs[addr] = seq
+		}
+		acc.UpdateSequence(seq)
+	}
+	if seq := acc.Sequence(); seq != ^uint64(0) { // EOAs have no storage
+		layer.seqToAddr[seq] = addr
+	}
+	return acc
+}
+
+// Return the overridden bytecode in the format of the underlying store, or ok=false if the bytecode is
+// not overridden. An empty bytecode is returned as nil, just like a missing one.
+func (layer *stateOverrideLayer) getBytecode(addr common.Address) (info *types.BytecodeInfo, ok bool) {
+	o, exist := layer.accounts[addr]
+	if !exist || o.Code == nil {
+		return nil, false
+	}
+	code := *o.Code
+	if len(code) == 0 {
+		return nil, true
+	}
+	bz := make([]byte, 33, 33+len(code))
+	bz[0] = 0 // version byte is zero
+	copy(bz[1:33], crypto.Keccak256(code))
+	return types.NewBytecodeInfo(append(bz, code...)), true
+}
+
+// Return the overridden value of a storage slot, or ok=false if the slot is not overridden
+func (layer *stateOverrideLayer) getValue(seq uint64, key string) (value []byte, ok bool) {
+	addr, exist := layer.seqToAddr[seq]
+	if !exist {
+		return nil, false
+	}
+	if _, written := layer.writtenValues[string(types.GetValueKey(seq, key))]; written {
+		return nil, false
+	}
+	o := layer.accounts[addr]
+	slots := o.StateDiff
+	if o.State != nil {
+		slots = o.State
+	}
+	v, exist := slots[common.BytesToHash([]byte(key))]
+	if !exist {
+		return nil, o.State != nil // the slots missing in State are empty
+	}
+	if v == (common.Hash{}) {
+		return nil, true
+	}
+	return v.Bytes(), true
+}
+
+// runTxHelper increases the sender's nonce before running the TX, so does the overridden nonce
+func (layer *stateOverrideLayer) increaseNonce(addr common.Address) {
+	if o, ok := layer.accounts[addr]; ok && o.Nonce != nil {
+		*o.Nonce++
+	}
+}
+
+// The functions below are called when collectResult writes changes to the underlying store, after
+// which the overridden values are stale and the written values must be used by the later calls.
+
+func (layer *stateOverrideLayer) accountWritten(addr common.Address) {
+	if o, ok := layer.accounts[addr]; ok {
+		o.Nonce = nil
+		o.Balance = nil
+	}
+}
+
+func (layer *stateOverrideLayer) bytecodeWritten(addr common.Address) {
+	if o, ok := layer.accounts[addr]; ok {
+		o.Code = nil
+	}
+}
+
+func (layer *stateOverrideLayer) valueWritten(valueKey []byte) {
+	layer.writtenValues[string(valueKey)] = struct{}{}
+}
@@ -0,0 +1,147 @@
+package ebp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"sort"
+)
+
+// A minimal encoder of the protobuf format of pprof, see github.com/google/pprof/proto/profile.proto
+
+type protoBuffer struct {
+	data []byte
+}
+
+func (b *protoBuffer) varint(x uint64) {
+	for x >= 0x80 {
+		b.data = append(b.data, byte(x)|0x80)
+		x >>= 7
+	}
+	b.data = append(b.data, byte(x))
+}
+
+func (b *protoBuffer) uint64Field(tag int, x uint64) {
+	b.varint(uint64(tag) << 3)
+	b.varint(x)
+}
+
+func (b *protoBuffer) packedField(tag int, xs []uint64) {
+	var packed protoBuffer
+	for _, x := range xs {
+		packed.varint(x)
+	}
+	b.bytesField(tag, packed.data)
+}
+
+func (b *protoBuffer) bytesField(tag int, bz []byte) {
+	b.varint(uint64(tag)<<3 | 2)
+	b.varint(uint64(len(bz)))
+	b.data = append(b.data, bz...)
+}
+
+// The field numbers in profile.proto
+const (
+	pprofSampleType    = 1
+	pprofSample        = 2
+	pprofLocation      = 4
+	pprofFunction      = 5
+	pprofStringTable   = 6
+	pprofDefaultType   = 14
+	valueTypeType      = 1
+	valueTypeUnit      = 2
+	sampleLocationID   = 1
+	sampleValue        = 2
+	locationID         = 1
+	locationLine       = 4
+	lineFunctionID     = 1
+	functionID         = 1
+	functionName       = 2
+	functionSystemName = 3
+)
+
+type pprofBuilder struct {
+	profile   protoBuffer
+	strings   map[string]uint64
+	functions map[string]uint64 // the function and location IDs are the same
+}
+
+func (pb *pprofBuilder) stringID(s string) uint64 {
+	id, ok := pb.strings[s]
+	if !ok {
+		id = uint64(len(pb.strings))
+		pb.strings[s] = id
+	}
+	return id
+}
+
+func (pb *pprofBuilder) locationID(name string) uint64 {
+	id, ok := pb.functions[name]
+	if ok {
+		return id
+	}
+	id = uint64(len(pb.functions) + 1)
+	pb.functions[name] = id
+	var fn protoBuffer
+	fn.uint64Field(functionID, id)
+	fn.uint64Field(functionName, pb.stringID(name))
+	fn.uint64Field(functionSystemName, pb.stringID(name))
+	pb.profile.bytesField(pprofFunction, fn.data)
+	var line protoBuffer
+	line.uint64Field(lineFunctionID, id)
+	var loc protoBuffer
+	loc.uint64Field(locationID, id)
+	loc.bytesField(locationLine, line.data)
+	pb.profile.bytesField(pprofLocation, loc.data)
+	return id
+}
+
+func (pb *pprofBuilder) valueType(tag int, typ, unit string) {
+	var vt protoBuffer
+	vt.uint64Field(valueTypeType, pb.stringID(typ))
+	vt.uint64Field(valueTypeUnit, pb.stringID(unit))
+	pb.profile.bytesField(tag, vt.data)
+}
+
+// Write Opcodes as a gzipped pprof profile, which can be viewed with "go tool pprof". Each sample's
+// stack has the contract as the caller and the opcode as the leaf, and its values are the gas and the
+// execution count.
+func (p *GasProfile) WritePprof(w io.Writer) error {
+	pb := &pprofBuilder{strings: map[string]uint64{"": 0}, functions: make(map[string]uint64)}
+	pb.valueType(pprofSampleType, "gas", "count")
+	pb.valueType(pprofSampleType, "executions", "count")
+	keys := make([]OpcodeKey, 0, len(p.Opcodes))
+	for key := range p.Opcodes {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { // for a deterministic output
+		if keys[i].Contract != keys[j].Contract {
+			return bytes.Compare(keys[i].Contract[:], keys[j].Contract[:]) < 0
+		}
+		return keys[i].Op < keys[j].Op
+	})
+	for _, key := range keys {
+		stat := p.Opcodes[key]
+		contract := key.Contract.Hex()
+		leaf := pb.locationID(contract + " " + key.Op.String())
+		root := pb.locationID(contract)
+		var sample protoBuffer
+		sample.packedField(sampleLocationID, []uint64{leaf, root})
+		sample.packedField(sampleValue, []uint64{stat.Gas, stat.Count})
+		pb.profile.bytesField(pprofSample, sample.data)
+	}
+	table := make([]string, len(pb.strings))
+	for s, id := range pb.strings {
+		table[id] = s
+	}
+	for _, s := range table {
+		pb.profile.bytesField(pprofStringTable, []byte(s))
+	}
+	pb.profile.uint64Field(pprofDefaultType, pb.stringID("gas"))
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(pb.profile.data); err != nil {
+		return err
+	}
+	return zw.Close()
+}
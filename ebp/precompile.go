@@ -8,6 +8,8 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/vechain/go-ecvrf"
+
+	"github.com/smartbch/moeingevm/types"
 )
 
 //#include <stdint.h>
@@ -59,7 +61,8 @@ func (vdfc *VrfVerifyContract) Run(input []byte) ([]byte, error) {
 }
 
 //export call_precompiled_contract
-func call_precompiled_contract(contract_addr *evmc_address,
+func call_precompiled_contract(handler C.int,
+	contract_addr *evmc_address,
 	input_ptr unsafe.Pointer,
 	input_size C.int,
 	gas_left *C.uint64_t,
@@ -69,11 +72,15 @@ func call_precompiled_contract(contract_addr *evmc_address,
 	output_size *C.int) {
 	*output_size = 0
 	addr := toAddress(contract_addr)
-	contract, ok := vm.PrecompiledContractsIstanbul[addr]
+	precompiles := vm.PrecompiledContractsIstanbul
+	if getRunner(int(handler)).Ctx.IsForkActive(types.BerlinFork) {
+		precompiles = vm.PrecompiledContractsBerlin // EIP-2565 reprices MODEXP
+	}
+	contract, ok := precompiles[addr]
 	if addr == common.Address([20]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x27, 0x13}) {
 		contract = &VrfVerifyContract{}
 		ok = true
-	} else if executor, exist := PredefinedContractManager[addr]; exist {
+	} else if executor, exist := getRunnerTable(int(handler)).predefinedContracts[addr]; exist {
 		contract = executor
 		ok = true
 	}
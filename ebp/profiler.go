@@ -0,0 +1,183 @@
+package ebp
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+
+	"github.com/smartbch/moeingevm/types"
+)
+
+// The gas used and the execution count of an opcode or a function
+type GasStat struct {
+	Gas   uint64
+	Count uint64
+}
+
+type OpcodeKey struct {
+	Contract common.Address // the address of the running code, i.e., the callee of DELEGATECALL
+	Op       vm.OpCode
+}
+
+type FunctionKey struct {
+	Contract common.Address
+	Selector [4]byte // zero for the calls whose input is shorter than 4 bytes
+}
+
+// Where the gas of one or more TXs goes
+type GasProfile struct {
+	// The gas used by the instructions themselves. For CALLs and CREATEs, the gas given to the callee is
+	// excluded, since the callee's instructions count it.
+	Opcodes map[OpcodeKey]*GasStat
+	// The gas used by the calls to each function, including the gas used by their sub calls. The contract
+	// creations are not included.
+	Functions map[FunctionKey]*GasStat
+	TxCount   uint64
+}
+
+func NewGasProfile() *GasProfile {
+	return &GasProfile{
+		Opcodes:   make(map[OpcodeKey]*GasStat),
+		Functions: make(map[FunctionKey]*GasStat),
+	}
+}
+
+func (p *GasProfile) opcodeStat(key OpcodeKey) *GasStat {
+	stat, ok := p.Opcodes[key]
+	if !ok {
+		stat = &GasStat{}
+		p.Opcodes[key] = stat
+	}
+	return stat
+}
+
+func (p *GasProfile) functionStat(key FunctionKey) *GasStat {
+	stat, ok := p.Functions[key]
+	if !ok {
+		stat = &GasStat{}
+		p.Functions[key] = stat
+	}
+	return stat
+}
+
+// Add the statistics of 'other' to 'p'
+func (p *GasProfile) Merge(other *GasProfile) {
+	for key, stat := range other.Opcodes {
+		s := p.opcodeStat(key)
+		s.Gas += stat.Gas
+		s.Count += stat.Count
+	}
+	for key, stat := range other.Functions {
+		s := p.functionStat(key)
+		s.Gas += stat.Gas
+		s.Count += stat.Count
+	}
+	p.TxCount += other.TxCount
+}
+
+// Add the function calls in the tree of 'frame' to Functions
+func (p *GasProfile) AddCallFrame(frame *types.CallFrame) {
+	if frame == nil {
+		return
+	}
+	if frame.Type != "CREATE" && frame.Type != "CREATE2" {
+		key := FunctionKey{Contract: frame.To}
+		if len(frame.Input) >= 4 {
+			copy(key.Selector[:], frame.Input[:4])
+		}
+		stat := p.functionStat(key)
+		stat.Gas += frame.GasUsed
+		stat.Count++
+	}
+	for _, sub := range frame.Calls {
+		p.AddCallFrame(sub)
+	}
+}
+
+// Add the function calls of a committed TX to Functions. Opcodes needs a GasProfiler during execution.
+func (p *GasProfile) AddTransaction(tx *types.Transaction) error {
+	frame, err := tx.CallFrame()
+	if err != nil {
+		return err
+	}
+	p.AddCallFrame(frame)
+	p.TxCount++
+	return nil
+}
+
+// A Tracer which adds the gas used by each opcode to a GasProfile
+type GasProfiler struct {
+	profile   *GasProfile
+	contracts []common.Address // the running code of the call frames
+	// The stat of the last instruction if it is a CALL or a CREATE, whose gas cost includes the gas
+	// given to the callee, and the gas cost
+	lastCall     *GasStat
+	lastCallCost uint64
+}
+
+func NewGasProfiler(profile *GasProfile) *GasProfiler {
+	return &GasProfiler{profile: profile}
+}
+
+func (p *GasProfiler) Profile() *GasProfile {
+	return p.profile
+}
+
+func (p *GasProfiler) CaptureEnter(depth int, address common.Address, gas uint64) {
+	if p.lastCall != nil { // the calling instruction is reported just before the callee starts
+		if gas > p.lastCallCost {
+			gas = p.lastCallCost
+		}
+		p.lastCall.Gas -= gas
+		p.lastCall = nil
+	}
+	p.contracts = append(p.contracts, address)
+}
+
+func (p *GasProfiler) CaptureState(log *StructLog) {
+	p.lastCall = nil
+	if len(p.contracts) == 0 {
+		return
+	}
+	stat := p.profile.opcodeStat(OpcodeKey{Contract: p.contracts[len(p.contracts)-1], Op: log.Op})
+	stat.Gas += log.GasCost
+	stat.Count++
+	switch log.Op {
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL, vm.CREATE, vm.CREATE2:
+		p.lastCall = stat
+		p.lastCallCost = log.GasCost
+	}
+}
+
+func (p *GasProfiler) CaptureExit(depth int, status int, output []byte, gasUsed uint64) {
+	p.lastCall = nil
+	if len(p.contracts) != 0 {
+		p.contracts = p.contracts[:len(p.contracts)-1]
+	}
+}
+
+// Run the runner's TX like RunTxForRpcWithContext and add its gas usage to 'profile'. The runner's Tracer
+// is replaced with a GasProfiler.
+func (exec *txEngine) ProfileTxForRpc(ctx context.Context, currBlock *types.BlockInfo, runner *TxRunner,
+	profile *GasProfile) error {
+	runner.ForRpc = true
+	runner.Tracer = NewGasProfiler(profile)
+	if _, err := exec.runnerTable.runTxForRpcWithContext(ctx, currBlock, false, runner); err != nil {
+		return err
+	}
+	frame, err := types.BuildCallFrame(runner.InternalTxCalls, runner.InternalTxReturns)
+	if err != nil {
+		return err
+	}
+	profile.AddCallFrame(frame)
+	profile.TxCount++
+	return nil
+}
+
+// Add the gas usage of the TXs committed by the following blocks to 'profile', or stop it with nil. Each
+// runner gets its own GasProfiler, which is merged into 'profile' only if the runner is committed. The
+// traced runners do not use AOT, so the execution is slower.
+func (exec *txEngine) SetGasProfile(profile *GasProfile) {
+	exec.gasProfile = profile
+}
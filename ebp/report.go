@@ -0,0 +1,81 @@
+package ebp
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/smartbch/moeingads/store/rabbit"
+
+	"github.com/smartbch/moeingevm/types"
+)
+
+// A TX marked as FAILED_TO_COMMIT because it touched a KV pair written by an earlier TX in the same round.
+// Either ShortKey or Key is set.
+type TxConflict struct {
+	TxHash   common.Hash
+	ShortKey [rabbit.KeySize]byte // the first conflicting short key found in its rabbit store
+	Key      []byte               // the conflicting original key, only found in the exact-key mode
+}
+
+// What happened in one round of 'Execute'
+type RoundReport struct {
+	// The TXs loaded from standby queue and executed in this round, in the order of runners
+	Loaded []common.Hash
+	// The executed TXs which must be re-executed later, because they conflicted with earlier TXs or
+	// the block gas limit was reached
+	FailedToCommit []common.Hash
+	// The TXs which were not executed because an earlier TX in the round has the same sender
+	NonceTooLarge []common.Hash
+	// The TXs skipped through 'ignoreList' (or held back by the DAG scheduler) without execution
+	Ignored   []common.Hash
+	Conflicts []TxConflict
+	// Wall-clock time of loading TXs, running them in parallel and committing the non-conflicting ones
+	LoadTime   time.Duration
+	RunTime    time.Duration
+	CommitTime time.Duration
+}
+
+// What happened during the last 'Execute', for tuning 'runnerNumber' and 'roundNum' and explaining
+// why some TX was delayed
+type ExecutionReport struct {
+	Height int64
+	// How many TXs were in standby queue before and after 'Execute'
+	StandbyQLenBefore int
+	StandbyQLenAfter  int
+	Rounds            []*RoundReport
+	// Wall-clock time of building the receipts and of the whole 'Execute'
+	CollectTime time.Duration
+	TotalTime   time.Duration
+}
+
+func (r *ExecutionReport) newRound() *RoundReport {
+	round := &RoundReport{}
+	r.Rounds = append(r.Rounds, round)
+	return round
+}
+
+func (r *ExecutionReport) currentRound() *RoundReport {
+	return r.Rounds[len(r.Rounds)-1]
+}
+
+// Classify the runner's TX by its status after committing
+func (round *RoundReport) recordRunner(runner *TxRunner) {
+	round.Loaded = append(round.Loaded, runner.Tx.HashID)
+	switch runner.Status {
+	case types.FAILED_TO_COMMIT:
+		round.FailedToCommit = append(round.FailedToCommit, runner.Tx.HashID)
+	case types.TX_NONCE_TOO_LARGE:
+		round.NonceTooLarge = append(round.NonceTooLarge, runner.Tx.HashID)
+	}
+}
+
+func (round *RoundReport) recordIgnored(txList []types.TxToRun) {
+	for _, tx := range txList {
+		round.Ignored = append(round.Ignored, tx.HashID)
+	}
+}
+
+// Return the report of the last 'Execute', or nil if 'Execute' has not been called
+func (exec *txEngine) ExecutionReport() *ExecutionReport {
+	return exec.report
+}
@@ -1,13 +1,14 @@
 package ebp
 
 import (
+	"context"
 	"encoding/binary"
-	"fmt"
-	"runtime"
+	"errors"
 	"sync/atomic"
 	"unsafe"
 
 	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/holiman/uint256"
 
 	"github.com/smartbch/moeingevm/types"
@@ -26,7 +27,13 @@ import (
 //                             int collector_handler,
 //                             bool need_gas_estimation,
 //                             enum evmc_revision revision,
-//                             bridge_query_executor_fn query_executor_fn);
+//                             bridge_query_executor_fn query_executor_fn,
+//                             volatile int32_t* abort_flag,
+//                             int64_t step_budget,
+//                             bool tracing,
+//                             bool trace_memory,
+//                             const struct access_list_item* access_list,
+//                             size_t access_list_size);
 import "C"
 
 type (
@@ -45,78 +52,20 @@ type (
 	block_info               = C.struct_block_info
 	big_buffer               = C.struct_big_buffer
 	small_buffer             = C.struct_small_buffer
-)
-
-const (
-	EnableRWList = false
+	access_list_item         = C.struct_access_list_item
 )
 
 var TotalBCHAmount [32]byte = uint256.NewInt(0).Mul(uint256.NewInt(1e18), uint256.NewInt(2100_0000)).Bytes32()
 
-var PredefinedContractManager map[common.Address]types.SystemContractExecutor
-
-func RegisterPredefinedContract(ctx *types.Context, address common.Address, executor types.SystemContractExecutor) {
-	PredefinedContractManager[address] = executor
-	if !executor.IsSystemContract(address) {
-		panic(fmt.Sprintf("contract %s is not system contract", address.String()))
-	}
-	executor.Init(ctx)
-}
-
-func init() {
-	PredefinedContractManager = make(map[common.Address]types.SystemContractExecutor)
-}
-
-// This is a global variable. The parameter 'collector_handler' passed to zero_depth_call_wrap is
-// an index to select one TxRunner from this global variable.
-var Runners []*TxRunner
-
 const (
 	RpcRunnersIdStart int = 10000
 	RpcRunnersCount   int = 256
 	SMALL_BUF_SIZE    int = int(C.SMALL_BUF_SIZE)
 )
 
-var AdjustGasUsed = true // It's a global variable because in tests we must change it to false to be compatible
-
-// Its usage is similar with Runners. Runners are for transactions in block. RpcRunners are for transactions
-// in Web3 RPC: call and estimateGas.
-var RpcRunners [RpcRunnersCount]*TxRunner
-
-var RpcRunnerLocks [RpcRunnersCount]spinLock
-
-type spinLock uint32
-
-func (sl *spinLock) Unlock() {
-	atomic.StoreUint32((*uint32)(sl), 0)
-}
-
-// try to abtain a lock and return false when failed
-func (sl *spinLock) TryLock() bool {
-	return atomic.CompareAndSwapUint32((*uint32)(sl), 0, 1)
-}
+var ErrStepBudgetExceeded = errors.New("execution step budget exceeded")
 
-func getFreeRpcRunnerAndLockIt() int {
-	for {
-		for i := range RpcRunnerLocks {
-			if RpcRunnerLocks[i].TryLock() {
-				return i
-			}
-		}
-		runtime.Gosched()
-	}
-}
-
-func getRunner(i int) (runner *TxRunner) {
-	if i < RpcRunnersIdStart {
-		runner = Runners[i]
-		runner.ForRpc = false
-	} else {
-		runner = RpcRunners[i-RpcRunnersIdStart]
-		runner.ForRpc = true
-	}
-	return
-}
+var AdjustGasUsed = true // It's a global variable because in tests we must change it to false to be compatible
 
 type TxRunner struct {
 	Ctx       *types.Context
@@ -134,6 +83,25 @@ type TxRunner struct {
 	InternalTxReturns []types.InternalTxReturn
 
 	RwLists *types.ReadWriteLists
+	// Whether to record the read/write operations in RwLists
+	EnableRWList bool
+
+	// Used by the exact-key mode, see exactkey.go
+	trackKeys    bool
+	accessedKeys []accessedKey
+
+	// Used by RunTxForRpcWithContext to abort the execution, see set_interrupt in bridge.h
+	interruptible bool
+	abortFlag     int32
+	stepBudget    int64
+
+	// Set by SetStateOverride, see override.go
+	overrides *stateOverrideLayer
+
+	// Receive the steps of EVM execution if not nil, see tracer.go
+	Tracer      Tracer
+	TraceConfig TraceConfig
+	traceState  *traceState
 }
 
 func NewTxRunner(ctx *types.Context, tx *types.TxToRun) *TxRunner {
@@ -188,17 +156,37 @@ func writeCBytes20WithArray(arr *evmc_address, bz [20]byte) {
 	}
 }
 
+// Flatten an access list into the items of set_access_list, each address is followed by its storage keys
+func toCAccessList(list gethtypes.AccessList) []access_list_item {
+	if len(list) == 0 {
+		return nil
+	}
+	items := make([]access_list_item, 0, len(list))
+	for _, tuple := range list {
+		var item access_list_item
+		writeCBytes20WithArray(&item.address, tuple.Address)
+		items = append(items, item)
+		for _, key := range tuple.StorageKeys {
+			item.has_key = C.bool(true)
+			writeCBytes32WithSlice(&item.key, key[:])
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 //Following are some getter/setter functions which provide world state to the C environment and
 //apply the changes made by the C environment to world state.
 
 func (runner *TxRunner) getCreationCounter(lsb uint8) uint64 {
 	k := types.GetCreationCounterKey(lsb)
 	v := runner.Ctx.Rbt.Get(k)
+	runner.recordKey(creationCounterKeyClass, k, false)
 	if v == nil {
 		return 0
 	}
 	counter := binary.BigEndian.Uint64(v)
-	if EnableRWList {
+	if runner.EnableRWList {
 		runner.RwLists.CreationCounterRList = append(runner.RwLists.CreationCounterRList,
 			types.CreationCounterRWOp{Lsb: lsb, Counter: counter})
 	}
@@ -210,7 +198,8 @@ func (runner *TxRunner) changeCreationCounter(chg_counter *changed_creation_coun
 	var buf [8]byte
 	binary.BigEndian.PutUint64(buf[:], uint64(chg_counter.counter))
 	runner.Ctx.Rbt.Set(k, buf[:])
-	if !EnableRWList {
+	runner.recordKey(creationCounterKeyClass, k, true)
+	if !runner.EnableRWList {
 		return
 	}
 	runner.RwLists.CreationCounterWList = append(runner.RwLists.CreationCounterWList,
@@ -220,6 +209,10 @@ func (runner *TxRunner) changeCreationCounter(chg_counter *changed_creation_coun
 func (runner *TxRunner) getAccountInfo(addr_ptr *evmc_address, balance *evmc_bytes32, nonce *C.uint64_t, sequence *C.uint64_t) {
 	addr := toAddress(addr_ptr)
 	acc := runner.Ctx.GetAccount(addr)
+	if runner.overrides != nil {
+		acc = runner.overrides.getAccount(addr, acc)
+	}
+	runner.recordKey(accountKeyClass, types.GetAccountKey(addr), false)
 	if acc == nil {
 		*nonce = ^C.uint64_t(0) // nonce with all ones means a non-existant account
 		return
@@ -227,7 +220,7 @@ func (runner *TxRunner) getAccountInfo(addr_ptr *evmc_address, balance *evmc_byt
 	writeCBytes32WithSlice(balance, acc.BalanceSlice())
 	*nonce = C.uint64_t(binary.BigEndian.Uint64(acc.NonceSlice()))
 	*sequence = C.uint64_t(binary.BigEndian.Uint64(acc.SequenceSlice()))
-	if !EnableRWList {
+	if !runner.EnableRWList {
 		return
 	}
 	op := types.AccountRWOp{Account: acc.Bytes(), Addr: addr}
@@ -247,7 +240,11 @@ func (runner *TxRunner) changeAccount(chg_acc *changed_account) {
 		writeSliceWithCBytes32(acc.BalanceSlice(), &chg_acc.balance)
 		runner.Ctx.Rbt.Set(k, acc.Bytes())
 	}
-	if !EnableRWList {
+	runner.recordKey(accountKeyClass, k, true)
+	if runner.overrides != nil {
+		runner.overrides.accountWritten(addr)
+	}
+	if !runner.EnableRWList {
 		return
 	}
 	if addr == runner.Tx.From {
@@ -259,7 +256,15 @@ func (runner *TxRunner) changeAccount(chg_acc *changed_account) {
 
 func (runner *TxRunner) getBytecode(addr_ptr *evmc_address, codehash_ptr *evmc_bytes32, buf *big_buffer, size *C.size_t) {
 	addr := toAddress(addr_ptr)
-	bi := runner.Ctx.GetCode(addr)
+	var bi *types.BytecodeInfo
+	overridden := false
+	if runner.overrides != nil {
+		bi, overridden = runner.overrides.getBytecode(addr)
+	}
+	if !overridden {
+		bi = runner.Ctx.GetCode(addr)
+	}
+	runner.recordKey(bytecodeKeyClass, types.GetBytecodeKey(addr), false)
 	if bi == nil {
 		*size = C.size_t(0)
 		return
@@ -270,7 +275,7 @@ func (runner *TxRunner) getBytecode(addr_ptr *evmc_address, codehash_ptr *evmc_b
 		buf.data[i] = C.uint8_t(bs[i])
 	}
 	writeCBytes32WithSlice(codehash_ptr, bi.CodeHashSlice())
-	if !EnableRWList {
+	if !runner.EnableRWList {
 		return
 	}
 	op := types.BytecodeRWOp{Bytecode: bi.Bytes(), Addr: addr}
@@ -290,7 +295,11 @@ func (runner *TxRunner) changeBytecode(chg_bytecode *changed_bytecode) {
 		bz = append(bz, C.GoStringN(chg_bytecode.bytecode_data, chg_bytecode.bytecode_size)...)
 		runner.Ctx.Rbt.Set(k, bz)
 	}
-	if !EnableRWList {
+	runner.recordKey(bytecodeKeyClass, k, true)
+	if runner.overrides != nil {
+		runner.overrides.bytecodeWritten(addr)
+	}
+	if !runner.EnableRWList {
 		return
 	}
 	op := types.BytecodeRWOp{Bytecode: bz, Addr: addr}
@@ -300,12 +309,20 @@ func (runner *TxRunner) changeBytecode(chg_bytecode *changed_bytecode) {
 func (runner *TxRunner) getValue(acc_seq C.uint64_t, key_ptr *C.char, buf *big_buffer, size *C.size_t) {
 	seq := uint64(acc_seq)
 	key := C.GoStringN(key_ptr, 32)
-	bs := runner.Ctx.GetStorageAt(seq, key)
+	var bs []byte
+	overridden := false
+	if runner.overrides != nil {
+		bs, overridden = runner.overrides.getValue(seq, key)
+	}
+	if !overridden {
+		bs = runner.Ctx.GetStorageAt(seq, key)
+	}
+	runner.recordKey(storageKeyClass, types.GetValueKey(seq, key), false)
 	*size = C.size_t(len(bs))
 	for i := range bs {
 		buf.data[i] = C.uint8_t(bs[i])
 	}
-	if !EnableRWList {
+	if !runner.EnableRWList {
 		return
 	}
 	op := types.StorageRWOp{Seq: seq, Key: key, Value: bs}
@@ -323,7 +340,11 @@ func (runner *TxRunner) changeValue(chg_value *changed_value) {
 		bz = C.GoBytes(unsafe.Pointer(chg_value.value_data), chg_value.value_size)
 		runner.Ctx.Rbt.Set(k, bz)
 	}
-	if !EnableRWList {
+	runner.recordKey(storageKeyClass, k, true)
+	if runner.overrides != nil {
+		runner.overrides.valueWritten(k)
+	}
+	if !runner.EnableRWList {
 		return
 	}
 	op := types.StorageRWOp{Seq: seq, Key: key, Value: bz}
@@ -334,7 +355,7 @@ func (runner *TxRunner) changeValue(chg_value *changed_value) {
 func (runner *TxRunner) getBlockHash(num C.uint64_t) (result evmc_bytes32) {
 	hash := runner.Ctx.GetBlockHashByHeight(uint64(num))
 	writeCBytes32WithSlice(&result, hash[:])
-	if !EnableRWList {
+	if !runner.EnableRWList {
 		return
 	}
 	op := types.BlockHashOp{Height: uint64(num), Hash: hash}
@@ -344,22 +365,30 @@ func (runner *TxRunner) getBlockHash(num C.uint64_t) (result evmc_bytes32) {
 
 // Refund gas fee to the sender according to the real consumed gas
 func (runner *TxRunner) refundGasFee(ret_value *evmc_result, refund C.uint64_t) {
-	if runner.ForRpc {
-		return
-	}
 	gasUsed := runner.Tx.Gas - uint64(ret_value.gas_left)
-	if AdjustGasUsed {
+	if AdjustGasUsed && !runner.ForRpc {
 		if gasUsed*4 < runner.Tx.Gas {
 			gasUsed = runner.Tx.Gas
 		} else if gasUsed*2 < runner.Tx.Gas {
 			gasUsed = (runner.Tx.Gas + gasUsed) / 2
 		}
 	}
-	half := (gasUsed + 1) / 2
-	if gasUsed < uint64(refund)+half { // can refund no more than half
-		gasUsed = half
-	} else {
+	if runner.Ctx.IsForkActive(types.LondonFork) { // EIP-3529: can refund no more than a fifth
+		if uint64(refund) > gasUsed/5 {
+			refund = C.uint64_t(gasUsed / 5)
+		}
 		gasUsed = gasUsed - uint64(refund)
+	} else {
+		half := (gasUsed + 1) / 2
+		if gasUsed < uint64(refund)+half { // can refund no more than half
+			gasUsed = half
+		} else {
+			gasUsed = gasUsed - uint64(refund)
+		}
+	}
+	if runner.ForRpc { // no gas fee is deducted for RPC, we just report the gas used
+		runner.GasUsed = gasUsed
+		return
 	}
 
 	k := types.GetAccountKey(runner.Tx.From)
@@ -375,9 +404,10 @@ func (runner *TxRunner) refundGasFee(ret_value *evmc_result, refund C.uint64_t)
 	x.Add(x, &returnedGasFee)
 	copy(acc.BalanceSlice(), utils.U256ToSlice32(x))
 	runner.Ctx.Rbt.Set(k, acc.Bytes())
+	runner.recordKey(accountKeyClass, k, true)
 	runner.FeeRefund = returnedGasFee
 	runner.GasUsed = gasUsed
-	if !EnableRWList {
+	if !runner.EnableRWList {
 		return
 	}
 	op := types.AccountRWOp{Account: acc.Bytes(), Addr: runner.Tx.From}
@@ -393,6 +423,19 @@ func (runner *TxRunner) GetGasFee() *uint256.Int {
 		
 }
 
+// The part of GetGasFee which is burnt according to EIP-1559, i.e., the base fee of the gas used. If the base
+// fee rises above the TX's gas price after Prepare, the whole gas fee is burnt.
+func (runner *TxRunner) GetBurntGasFee(baseFee *uint256.Int) *uint256.Int {
+	gasPrice := uint256.NewInt(0).SetBytes(runner.Tx.GasPrice[:])
+	if gasPrice.GtUint64(MaxGasPrice) {
+		gasPrice = uint256.NewInt(MaxGasPrice)
+	}
+	if baseFee.Lt(gasPrice) {
+		gasPrice = baseFee
+	}
+	return uint256.NewInt(0).Mul(uint256.NewInt(runner.GasUsed), gasPrice)
+}
+
 func convertLog(log *added_log) (res types.EvmLog) {
 	if log.topic1 != nil {
 		res.Topics = append(res.Topics, toHash(log.topic1))
@@ -540,31 +583,73 @@ func get_block_hash(handler C.int, num C.uint64_t) (result evmc_bytes32) {
 	return getRunner(int(handler)).getBlockHash(num)
 }
 
-func runTx(idx int, currBlock *types.BlockInfo) {
-	runTxHelper(idx, currBlock, false)
+func (t *runnerTable) runTx(idx int, currBlock *types.BlockInfo) {
+	runTxHelper(t.handler(idx), currBlock, false)
 }
 
-func RunTxForRpc(currBlock *types.BlockInfo, estimateGas bool, runner *TxRunner) int64 {
+func (t *runnerTable) runTxForRpc(currBlock *types.BlockInfo, estimateGas bool, runner *TxRunner) int64 {
 	//fmt.Printf("RunTxForRpc height %d\n", currBlock.Number)
-	idx := getFreeRpcRunnerAndLockIt()
-	RpcRunners[idx] = runner
-	defer func() {
-		RpcRunners[idx] = nil
-		RpcRunnerLocks[idx].Unlock()
-	}()
-	return runTxHelper(idx+RpcRunnersIdStart, currBlock, estimateGas)
+	idx, _ := t.acquireRpcRunner(context.Background(), false) // never fails
+	t.rpcRunners[idx] = runner
+	defer t.releaseRpcRunner(idx)
+	return runTxHelper(t.handler(idx+RpcRunnersIdStart), currBlock, estimateGas)
+}
+
+// Like runTxForRpc, but it fails fast when too many callers are waiting for idle runners, and the
+// execution is aborted once 'ctx' is done or the step budget is exhausted.
+func (t *runnerTable) runTxForRpcWithContext(ctx context.Context, currBlock *types.BlockInfo, estimateGas bool,
+	runner *TxRunner) (int64, error) {
+	idx, err := t.acquireRpcRunner(ctx, true)
+	if err != nil {
+		return 0, err
+	}
+	t.rpcRunners[idx] = runner
+	defer t.releaseRpcRunner(idx)
+	runner.interruptible = true
+	runner.abortFlag = 0
+	runner.stepBudget = atomic.LoadInt64(&t.rpcStepBudget)
+	stopWatcher := func() {}
+	if done := ctx.Done(); done != nil {
+		finished := make(chan struct{})
+		exited := make(chan struct{})
+		go func() {
+			defer close(exited)
+			select {
+			case <-done:
+				atomic.CompareAndSwapInt32(&runner.abortFlag, 0, C.ABORT_CANCELLED)
+			case <-finished:
+			}
+		}()
+		stopWatcher = func() {
+			close(finished)
+			<-exited
+		}
+	}
+	gasEstimated := runTxHelper(t.handler(idx+RpcRunnersIdStart), currBlock, estimateGas)
+	stopWatcher()
+	switch atomic.LoadInt32(&runner.abortFlag) {
+	case C.ABORT_CANCELLED:
+		// ctx may be done after the execution finished, in which case the result is kept
+		if runner.Status == int(C.EVMC_INTERNAL_ERROR) {
+			return 0, ctx.Err()
+		}
+	case C.ABORT_STEP_BUDGET:
+		return 0, ErrStepBudgetExceeded
+	}
+	return gasEstimated, nil
 }
 
-//Start the idx-th TxRunner to run the transaction assigned to it beforehand.
+//Start the TxRunner selected by 'handler' to run the transaction assigned to it beforehand.
 //In this function Go data structures are converted to C data structures and finally
 //call the C entrance function 'zero_depth_call_wrap'.
-func runTxHelper(idx int, currBlock *types.BlockInfo, estimateGas bool) int64 {
-	runner := getRunner(idx)
+func runTxHelper(handler int, currBlock *types.BlockInfo, estimateGas bool) int64 {
+	runner := getRunner(handler)
 	if !runner.ForRpc && runner.Tx.Height+types.TOO_OLD_THRESHOLD < uint64(currBlock.Number) {
 		runner.Status = types.IGNORE_TOO_OLD_TX
 		return 0
 	}
 	acc, err := runner.Ctx.CheckNonce(runner.Tx.From, runner.Tx.Nonce)
+	runner.recordKey(accountKeyClass, types.GetAccountKey(runner.Tx.From), false)
 	if !runner.ForRpc && err != nil { // For RPC, we do not care about sender and its nonce
 		if err == types.ErrAccountNotExist {
 			runner.Status = types.ACCOUNT_NOT_EXIST
@@ -581,6 +666,10 @@ func runTxHelper(idx int, currBlock *types.BlockInfo, estimateGas bool) int64 {
 		// GasFee was deducted in Prepare(), so here we just increase the nonce
 		acc.UpdateNonce(acc.Nonce() + 1)
 		runner.Ctx.SetAccount(runner.Tx.From, acc)
+		runner.recordKey(accountKeyClass, types.GetAccountKey(runner.Tx.From), true)
+	}
+	if runner.overrides != nil {
+		runner.overrides.increaseNonce(runner.Tx.From)
 	}
 	var value, gas_price evmc_bytes32
 	var to, from evmc_address
@@ -594,15 +683,22 @@ func runTxHelper(idx int, currBlock *types.BlockInfo, estimateGas bool) int64 {
 	bi.number = C.int64_t(currBlock.Number)
 	bi.timestamp = C.int64_t(currBlock.Timestamp)
 	bi.gas_limit = C.int64_t(currBlock.GasLimit)
-	bi.cfg.after_xhedge_fork = C.bool(runner.Ctx.IsXHedgeFork())
-	bi.cfg.after_symbolsbch_fork = C.bool(runner.Ctx.IsSymbolSbchFork())
+	bi.cfg = newForkConfig(runner.Ctx)
 	writeCBytes32WithSlice(&bi.difficulty, currBlock.Difficulty[:])
 	writeCBytes32WithSlice(&bi.chain_id, currBlock.ChainId[:])
+	writeCBytes32WithSlice(&bi.base_fee, currBlock.BaseFee[:])
 	data_ptr := (*C.uint8_t)(nil)
 	if len(runner.Tx.Data) != 0 {
 		data_ptr = (*C.uint8_t)(unsafe.Pointer(&runner.Tx.Data[0]))
 	}
-	if executor, exist := PredefinedContractManager[runner.Tx.To]; exist {
+	abort_flag := (*C.int32_t)(nil)
+	step_budget := C.int64_t(-1)
+	if runner.interruptible {
+		abort_flag = (*C.int32_t)(unsafe.Pointer(&runner.abortFlag))
+		step_budget = C.int64_t(runner.stepBudget)
+	}
+	if executor, exist := getRunnerTable(handler).predefinedContracts[runner.Tx.To]; exist {
+		runner.trackKeys = false // the keys accessed by system contracts are unknown
 		status, logs, gasUsed, out := executor.Execute(runner.Ctx, currBlock, runner.Tx)
 		runner.Status = status
 		runner.Logs = logs
@@ -611,6 +707,25 @@ func runTxHelper(idx int, currBlock *types.BlockInfo, estimateGas bool) int64 {
 		return int64(gasUsed)
 	}
 
+	revision := C.enum_evmc_revision(C.EVMC_ISTANBUL)
+	accessList := []access_list_item(nil)
+	if runner.Ctx.IsForkActive(types.BerlinFork) {
+		revision = C.EVMC_BERLIN
+		accessList = toCAccessList(runner.Tx.AccessList)
+	}
+	if runner.Ctx.IsForkActive(types.LondonFork) {
+		revision = C.EVMC_LONDON
+	}
+	if runner.Ctx.IsForkActive(types.ShanghaiFork) {
+		revision = C.EVMC_SHANGHAI
+	}
+	if runner.Ctx.IsForkActive(types.CancunFork) {
+		revision = C.EVMC_CANCUN
+	}
+	access_list_ptr := (*access_list_item)(nil)
+	if len(accessList) != 0 {
+		access_list_ptr = &accessList[0]
+	}
 	gasEstimated := C.zero_depth_call_wrap(gas_price,
 		C.int64_t(runner.Tx.Gas),
 		&to,
@@ -619,10 +734,16 @@ func runTxHelper(idx int, currBlock *types.BlockInfo, estimateGas bool) int64 {
 		data_ptr,
 		C.size_t(len(runner.Tx.Data)),
 		&bi,
-		C.int(idx),
+		C.int(handler),
 		C.bool(estimateGas),
-		C.EVMC_ISTANBUL,
-		QueryExecutorFn)
+		revision,
+		QueryExecutorFn,
+		abort_flag,
+		step_budget,
+		C.bool(runner.Tracer != nil),
+		C.bool(runner.TraceConfig.EnableMemory),
+		access_list_ptr,
+		C.size_t(len(accessList)))
 	return int64(gasEstimated)
 }
 
@@ -630,6 +751,10 @@ func StatusIsFailure(status int) bool {
 	return status != int(C.EVMC_SUCCESS)
 }
 
+func StatusIsRevert(status int) bool {
+	return status == int(C.EVMC_REVERT)
+}
+
 func StatusToStr(status int) string {
 	switch status {
 	case int(C.EVMC_SUCCESS):
@@ -0,0 +1,156 @@
+package ebp
+
+import (
+	"sync/atomic"
+	"time"
+
+	dt "github.com/smartbch/moeingads/datatree"
+	storetypes "github.com/smartbch/moeingads/store/types"
+
+	"github.com/smartbch/moeingevm/types"
+)
+
+// The DAG scheduler is an alternative to the round-based scheduler in Execute. Instead of pushing every
+// conflicting TX back to the end of the standby queue, it keeps a window of at most 'runnerNumber' TXs which
+// were taken from the head of the standby queue. The read/write lists recorded in rwListMap turn the window
+// into a dependency DAG: a TX depends on an earlier TX in the window if they have the same sender, or if it
+// reads or writes a KV pair written by the earlier TX. In each round, only the TXs without any dependency
+// in the window are executed, and the committed ones leave the window. The rounds are still limited by
+// 'roundNum', and the TXs left in the window are inserted back into the standby queue in their order.
+// The DAG only depends on the recorded read/write lists, so the result is deterministic and independent
+// of 'parallelNum'.
+
+// Take TXs from the head of the standby queue and append them to the window, until the window is full
+func (exec *txEngine) fillWindow(txRange *TxRange, window []types.TxToRun) []types.TxToRun {
+	if len(window) >= exec.runnerNumber || txRange.start == txRange.end {
+		return window
+	}
+	ctx := exec.cleanCtx.WithRbtCopy()
+	oldStart := txRange.start
+	for ; txRange.start < txRange.end && len(window) < exec.runnerNumber; txRange.start++ {
+		bz := ctx.Rbt.GetBaseStore().Get(types.GetStandbyTxKey(txRange.start))
+		var txToRun types.TxToRun
+		txToRun.FromBytes(bz)
+		window = append(window, txToRun)
+	}
+	ctx.Close(false)
+	trunk := exec.cleanCtx.Rbt.GetBaseStore()
+	trunk.Update(func(store storetypes.SetDeleter) {
+		for i := oldStart; i < txRange.start; i++ {
+			store.Delete(types.GetStandbyTxKey(i)) // remove it from the standby queue
+		}
+	})
+	return window
+}
+
+// Return the positions of the TXs in the window which do not depend on any earlier TX in the window.
+// A TX without recorded read/write list is optimistically taken as independent, except for the
+// same-sender dependency, which is always known.
+func (exec *txEngine) getReadyTxs(window []types.TxToRun) (readyIdxList []int) {
+	writtenSet := make(map[uint64]struct{}, 32*len(window))
+	senderSet := make(map[[20]byte]struct{}, len(window))
+	readyIdxList = make([]int, 0, len(window))
+	for idx, tx := range window {
+		_, sameSender := senderSet[tx.From]
+		rwList, isRecorded := exec.rwListMap[tx.HashID]
+		if !sameSender && !(isRecorded && rwList.conflictsWith(writtenSet)) {
+			readyIdxList = append(readyIdxList, idx)
+		}
+		senderSet[tx.From] = struct{}{}
+		rwList.updateTouchedSet(writtenSet) // the later TXs depend on it if they touch what it writes
+	}
+	return
+}
+
+// Assign the ready TXs to 'exec.runners' and run them in parallel.
+// Return the count of touched KV pairs as a hint for commitNonConflictingRunners
+func (exec *txEngine) runReadyTxs(window []types.TxToRun, readyIdxList []int, currBlock *types.BlockInfo) (kvCount int64) {
+	sharedIdx := int64(-1)
+	dt.ParallelRun(exec.parallelNum, func(_ int) {
+		for {
+			myIdx := atomic.AddInt64(&sharedIdx, 1)
+			if myIdx >= int64(len(readyIdxList)) {
+				return
+			}
+			exec.runners[myIdx] = exec.newTxRunner(&window[readyIdxList[myIdx]])
+			exec.runnerTable.runTx(int(myIdx), currBlock)
+			atomic.AddInt64(&kvCount, int64(exec.runners[myIdx].Ctx.Rbt.CachedEntryCount()))
+		}
+	})
+	return
+}
+
+// Run one round of the DAG scheduler. The committable runners are appended to 'committableRunnerList' and
+// the TXs which must be re-executed are kept in the returned window.
+func (exec *txEngine) executeOneDAGRound(window []types.TxToRun, committableRunnerList []*TxRunner,
+	currBlock *types.BlockInfo) ([]types.TxToRun, []*TxRunner) {
+	round := exec.report.currentRound()
+	startTime := time.Now()
+	readyIdxList := exec.getReadyTxs(window)
+	round.LoadTime += time.Since(startTime)
+	startTime = time.Now()
+	kvCount := exec.runReadyTxs(window, readyIdxList, currBlock)
+	round.RunTime = time.Since(startTime)
+	startTime = time.Now()
+	exec.commitNonConflictingRunners(len(readyIdxList), int(kvCount), true)
+	round.CommitTime = time.Since(startTime)
+	exec.txExecutedCount += len(readyIdxList)
+
+	leaving := make(map[int]struct{}, len(readyIdxList))
+	for i, idx := range readyIdxList {
+		runner := exec.runners[i]
+		exec.runners[i] = nil
+		round.recordRunner(runner)
+		status := runner.Status
+		if status == types.FAILED_TO_COMMIT || status == types.TX_NONCE_TOO_LARGE {
+			continue // stay in the window and wait for re-execution
+		}
+		leaving[idx] = struct{}{}
+		if status == types.ACCOUNT_NOT_EXIST || status == types.TX_NONCE_TOO_SMALL {
+			//collect invalid tx`s all gas
+			exec.cumulativeGasUsed += runner.Tx.Gas
+			exec.collectGasFee(runner)
+		} else {
+			committableRunnerList = append(committableRunnerList, runner)
+		}
+	}
+	// the runners refer to the TXs in the old window, so we must allocate a new one
+	newWindow := make([]types.TxToRun, 0, exec.runnerNumber)
+	ready := make(map[int]struct{}, len(readyIdxList))
+	for _, idx := range readyIdxList {
+		ready[idx] = struct{}{}
+	}
+	for idx, tx := range window {
+		if _, ok := ready[idx]; !ok {
+			round.Ignored = append(round.Ignored, tx.HashID) // held back by its dependency
+		}
+		if _, ok := leaving[idx]; !ok {
+			newWindow = append(newWindow, tx)
+		}
+	}
+	return newWindow, committableRunnerList
+}
+
+// Execute TXs in the standby queue with the DAG scheduler and return all the committable runners
+func (exec *txEngine) executeWithDAG(txRange *TxRange, currBlock *types.BlockInfo) []*TxRunner {
+	committableRunnerList := make([]*TxRunner, 0, 4096)
+	window := make([]types.TxToRun, 0, exec.runnerNumber)
+	for i := 0; i < exec.roundNum; i++ {
+		startTime := time.Now()
+		window = exec.fillWindow(txRange, window)
+		if len(window) == 0 {
+			break
+		}
+		exec.report.newRound().LoadTime = time.Since(startTime)
+		window, committableRunnerList = exec.executeOneDAGRound(window, committableRunnerList, currBlock)
+		if exec.gasLimitReached {
+			break
+		}
+	}
+	// insert the unfinished TXs back into standby queue
+	trunk := exec.cleanCtx.Rbt.GetBaseStore()
+	trunk.Update(func(store storetypes.SetDeleter) {
+		exec.insertBackToStandbyQ(store, txRange, window)
+	})
+	return committableRunnerList
+}
@@ -0,0 +1,176 @@
+package ebp
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/holiman/uint256"
+	"github.com/smartbch/moeingads/store"
+	"github.com/smartbch/moeingads/store/rabbit"
+	storetypes "github.com/smartbch/moeingads/store/types"
+
+	"github.com/smartbch/moeingevm/types"
+)
+
+// overlayStore buffers all the writes in memory and never writes them to its parent, so that a
+// simulation can run on it as if it were the trunk store.
+type overlayStore struct {
+	parent storetypes.BaseStoreI
+	mtx    sync.RWMutex
+	cache  *store.CacheStore
+}
+
+var _ storetypes.BaseStoreI = (*overlayStore)(nil)
+
+func newOverlayStore(parent storetypes.BaseStoreI) *overlayStore {
+	return &overlayStore{
+		parent: parent,
+		cache:  store.NewCacheStore(4096),
+	}
+}
+
+func (o *overlayStore) RLock() {
+	o.parent.RLock()
+}
+
+func (o *overlayStore) RUnlock() {
+	o.parent.RUnlock()
+}
+
+func (o *overlayStore) Get(key []byte) []byte {
+	o.mtx.RLock()
+	res, status := o.cache.Get(key)
+	o.mtx.RUnlock()
+	switch status {
+	case storetypes.JustDeleted:
+		return nil
+	case storetypes.Hit:
+		return append([]byte{}, res...)
+	default:
+		return o.parent.Get(key)
+	}
+}
+
+func (o *overlayStore) GetAtHeight(key []byte, height uint64) []byte {
+	return o.parent.GetAtHeight(key, height)
+}
+
+// Nothing will be written to the parent, so it need not be warmed up
+func (o *overlayStore) PrepareForUpdate(key []byte) {}
+
+func (o *overlayStore) PrepareForDeletion(key []byte) {}
+
+func (o *overlayStore) Update(updater func(db storetypes.SetDeleter)) {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	updater(o.cache)
+}
+
+func (o *overlayStore) ActiveCount() int {
+	return o.parent.ActiveCount()
+}
+
+// A KV pair of world state changed by a simulation
+type StateChange struct {
+	Key   []byte // the original key, such as the ones returned by types.GetAccountKey
+	Value []byte // nil means the KV pair is deleted
+}
+
+// Only the KV pairs accessed through rabbit stores are world state; the standby queue is outside this range
+func isRabbitKey(key []byte) bool {
+	return len(key) == rabbit.KeySize && key[0] >= 64 && key[0] < 64+128
+}
+
+// Decode a rabbit hole into the KV pair stored in it. 'ok' is false if it contains no KV pair.
+func decodeRabbitHole(bz []byte) (key, value []byte, ok bool) {
+	if len(bz) == 0 {
+		return nil, nil, false
+	}
+	cv := rabbit.BytesToCachedValue(bz)
+	if cv == nil || cv.IsEmpty() {
+		return nil, nil, false
+	}
+	return cv.GetKey(), cv.GetValue(), true
+}
+
+// Return the KV pairs of world state whose values in the overlay differ from the ones in the parent, sorted by keys
+func (o *overlayStore) stateChanges() []StateChange {
+	changes := make([]StateChange, 0, o.cache.Size())
+	o.cache.ScanAllEntries(func(k, v []byte, isDeleted bool) {
+		if !isRabbitKey(k) {
+			return
+		}
+		oldKey, oldValue, oldOk := decodeRabbitHole(o.parent.Get(k))
+		var newKey, newValue []byte
+		newOk := false
+		if !isDeleted {
+			newKey, newValue, newOk = decodeRabbitHole(v)
+		}
+		if newOk && !(oldOk && bytes.Equal(oldKey, newKey) && bytes.Equal(oldValue, newValue)) {
+			changes = append(changes, StateChange{Key: newKey, Value: newValue})
+		}
+		if oldOk && !(newOk && bytes.Equal(oldKey, newKey)) {
+			changes = append(changes, StateChange{Key: oldKey})
+		}
+	})
+	sort.Slice(changes, func(i, j int) bool {
+		return bytes.Compare(changes[i].Key, changes[j].Key) < 0
+	})
+	return changes
+}
+
+// The outcome of SimulateBlock
+type SimulationResult struct {
+	// The receipts of the TXs rejected by 'Prepare'
+	InvalidTxs []*types.Transaction
+	// The receipts of the TXs executed by 'Execute', the same as what CommittedTxs returns
+	Receipts  []*types.Transaction
+	GasUsed   uint64
+	FeeRefund uint256.Int
+	GasFee    uint256.Int
+	BurntFee  uint256.Int // the part of the gas fees moved to the black hole account, excluded by GasFee
+	// The TXs left in standby queue after 'Execute'
+	StandbyTxs []StandbyTx
+	// The changed KV pairs of world state, excluding the standby queue
+	StateChanges []StateChange
+	// What happened during 'Execute'
+	Report *ExecutionReport
+}
+
+// Run 'Prepare' and 'Execute' for 'txs' on a throw-away copy of the trunk store beneath the current context,
+// using a temporary engine with the same parameters and system contracts. Nothing is written back, and the
+// state of this engine is not changed. The minimal gas price and maximal gas limit used by the last 'Prepare'
+// are used to validate 'txs'.
+func (exec *txEngine) SimulateBlock(txs []*gethtypes.Transaction, blockInfo *types.BlockInfo, reorderSeed int64) *SimulationResult {
+	overlay := newOverlayStore(exec.cleanCtx.Rbt.GetBaseStore())
+	newCtx := func() *types.Context {
+		rbt := rabbit.NewRabbitStore(overlay)
+		return exec.cleanCtx.WithRbt(&rbt)
+	}
+	sim := NewEbpTxExec(exec.roundNum, exec.runnerNumber, exec.parallelNum, len(txs), exec.signer, exec.orderer, exec.logger)
+	defer sim.Close()
+	sim.checkRWInLoading = exec.checkRWInLoading
+	sim.useDAGScheduler = exec.useDAGScheduler
+	sim.exactKeyConfig = exec.exactKeyConfig
+	for addr, executor := range exec.runnerTable.predefinedContracts {
+		sim.runnerTable.predefinedContracts[addr] = executor // they are already initialized
+	}
+	for _, tx := range txs {
+		sim.CollectTx(tx)
+	}
+	sim.currentBlock = blockInfo // the TXs are inserted into standby queue at this height
+	sim.SetContext(newCtx())
+	sim.Prepare(reorderSeed, exec.minGasPrice, exec.maxTxGasLimit, uint256.NewInt(0).SetBytes32(blockInfo.BaseFee[:]))
+	res := &SimulationResult{InvalidTxs: append([]*types.Transaction{}, sim.committedTxs...)}
+	sim.SetContext(newCtx())
+	sim.Execute(blockInfo)
+	res.Receipts = sim.committedTxs
+	res.GasUsed, res.FeeRefund, res.GasFee, res.BurntFee = sim.GasUsedInfo()
+	res.Report = sim.ExecutionReport()
+	res.StandbyTxs = sim.GetStandbyTxs(blockInfo.Number, 0, sim.StandbyQLen())
+	sim.cleanCtx.Close(false)
+	res.StateChanges = overlay.stateChanges()
+	return res
+}
@@ -0,0 +1,130 @@
+package ebp
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	storetypes "github.com/smartbch/moeingads/store/types"
+
+	"github.com/smartbch/moeingevm/types"
+)
+
+// A TX waiting in the standby queue, viewed at some height
+type StandbyTx struct {
+	Position uint64 // its key in standby queue is types.GetStandbyTxKey(Position)
+	Tx       types.TxToRun
+	Age      uint64 // how many blocks have passed since it was inserted into standby queue
+	TooOld   bool   // Age is larger than types.TOO_OLD_THRESHOLD, so it will not be executed
+}
+
+func newStandbyTx(pos uint64, tx types.TxToRun, height int64) StandbyTx {
+	res := StandbyTx{Position: pos, Tx: tx}
+	if uint64(height) > tx.Height {
+		res.Age = uint64(height) - tx.Height
+	}
+	res.TooOld = res.Age > types.TOO_OLD_THRESHOLD
+	return res
+}
+
+// Visit the TXs in standby queue from its head, until 'fn' returns true
+func (exec *txEngine) scanStandbyQ(fn func(pos uint64, tx types.TxToRun) (stop bool)) {
+	start, end := exec.getStandbyQueueRange()
+	ctx := exec.cleanCtx.WithRbtCopy()
+	defer ctx.Close(false)
+	for i := start; i < end; i++ {
+		var txToRun types.TxToRun
+		txToRun.FromBytes(ctx.Rbt.GetBaseStore().Get(types.GetStandbyTxKey(i)))
+		if fn(i, txToRun) {
+			return
+		}
+	}
+}
+
+// Return at most 'limit' TXs in standby queue, skipping the first 'offset' ones. Their ages are
+// calculated with 'height'.
+func (exec *txEngine) GetStandbyTxs(height int64, offset, limit int) []StandbyTx {
+	res := make([]StandbyTx, 0, limit)
+	if limit <= 0 {
+		return res
+	}
+	start, end := exec.getStandbyQueueRange()
+	if offset < 0 || uint64(offset) >= end-start {
+		return res
+	}
+	ctx := exec.cleanCtx.WithRbtCopy()
+	defer ctx.Close(false)
+	for i := start + uint64(offset); i < end && len(res) < limit; i++ {
+		var txToRun types.TxToRun
+		txToRun.FromBytes(ctx.Rbt.GetBaseStore().Get(types.GetStandbyTxKey(i)))
+		res = append(res, newStandbyTx(i, txToRun, height))
+	}
+	return res
+}
+
+func (exec *txEngine) GetStandbyTxByHash(height int64, txHash common.Hash) (res StandbyTx, found bool) {
+	exec.scanStandbyQ(func(pos uint64, tx types.TxToRun) bool {
+		if tx.HashID == txHash {
+			res, found = newStandbyTx(pos, tx, height), true
+		}
+		return found
+	})
+	return
+}
+
+// Return the TXs sent by 'sender' in standby queue, in their order in the queue
+func (exec *txEngine) GetStandbyTxsBySender(height int64, sender common.Address) []StandbyTx {
+	res := make([]StandbyTx, 0, 8)
+	exec.scanStandbyQ(func(pos uint64, tx types.TxToRun) bool {
+		if tx.From == sender {
+			res = append(res, newStandbyTx(pos, tx, height))
+		}
+		return false
+	})
+	return res
+}
+
+// Remove the TXs which are too old or sent by blocked accounts from standby queue, and record failure receipts
+// for them in 'committedTxs'. The remaining TXs keep their order. It must be called after 'Execute', at the
+// same point of every block on all the nodes, because it changes the world state. Returns the number of
+// removed TXs.
+func (exec *txEngine) PurgeStandbyQ() int {
+	if exec.currentBlock == nil {
+		return 0
+	}
+	start, end := exec.getStandbyQueueRange()
+	keptTxs := make([]types.TxToRun, 0, end-start)
+	purgedInfos := make([]*preparedInfo, 0, 8)
+	ctx := exec.cleanCtx.WithRbtCopy()
+	ctx.SetCurrentHeight(exec.currentBlock.Number)
+	exec.scanStandbyQ(func(pos uint64, tx types.TxToRun) bool {
+		txCopy := tx
+		if isBlockedSender(ctx, tx.From) {
+			purgedInfos = append(purgedInfos, &preparedInfo{tx: &txCopy,
+				rejection: &types.TxRejection{Code: types.REJECTED_BLOCKED_SENDER}})
+		} else if newStandbyTx(pos, tx, exec.currentBlock.Number).TooOld {
+			purgedInfos = append(purgedInfos, &preparedInfo{tx: &txCopy,
+				rejection: &types.TxRejection{Code: types.REJECTED_TOO_OLD}})
+		} else {
+			keptTxs = append(keptTxs, tx)
+		}
+		return false
+	})
+	ctx.Close(false)
+	if len(purgedInfos) == 0 {
+		return 0
+	}
+	// the remaining TXs are moved to the end of the queue, and the freed positions at the head are deleted
+	newStart := end - uint64(len(keptTxs))
+	trunk := exec.cleanCtx.Rbt.GetBaseStore()
+	trunk.Update(func(store storetypes.SetDeleter) {
+		for i := start; i < newStart; i++ {
+			store.Delete(types.GetStandbyTxKey(i))
+		}
+		for i, tx := range keptTxs {
+			store.Set(types.GetStandbyTxKey(newStart+uint64(i)), tx.ToBytes())
+		}
+	})
+	exec.setStandbyQueueRange(newStart, end)
+	for _, info := range purgedInfos {
+		exec.recordInvalidTx(info)
+	}
+	return len(purgedInfos)
+}
@@ -0,0 +1,238 @@
+package ebp
+
+//#include "../evmwrap/host_bridge/bridge.h"
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/holiman/uint256"
+)
+
+// Which parts of the EVM state are recorded in StructLog, like geth's LogConfig
+type TraceConfig struct {
+	EnableMemory   bool
+	DisableStack   bool
+	DisableStorage bool
+}
+
+// The EVM state before executing one instruction, like geth's StructLog
+type StructLog struct {
+	Pc      uint64
+	Op      vm.OpCode
+	Gas     uint64
+	GasCost uint64 // for CALLs and CREATEs, it includes the gas given to the sub call, like geth
+	Depth   int    // 1 for the TX itself
+	Stack   []uint256.Int
+	Memory  []byte
+	// The storage slots of the current contract accessed so far, only recorded for SLOAD and SSTORE
+	Storage map[common.Hash]common.Hash
+}
+
+// A Tracer receives the steps of EVM execution, when it is assigned to TxRunner.Tracer.
+// The call frames without bytecode to run, such as transfers and precompiled contracts, are not reported.
+// The predefined contracts and the AOT-compiled contracts are not traced.
+type Tracer interface {
+	// A call frame starts to run bytecode at 'address', with 'gas' available. For DELEGATECALL and CALLCODE,
+	// 'address' is the callee whose code runs, not the caller whose storage is used.
+	CaptureEnter(depth int, address common.Address, gas uint64)
+	// An instruction is executed, which is reported once its gas cost is known, i.e., before the next
+	// instruction or the sub call it starts
+	CaptureState(log *StructLog)
+	// A call frame exits with 'status', which is an evmc_status_code
+	CaptureExit(depth int, status int, output []byte, gasUsed uint64)
+}
+
+type traceFrame struct {
+	storageAddr common.Address
+	startGas    uint64
+	pending     *StructLog // the last instruction, whose gas cost is unknown yet
+	// the pending instruction is an SLOAD of 'sloadKey', whose value will be on the stack top of the next instruction
+	pendingSload bool
+	sloadKey     common.Hash
+}
+
+// The state of a runner's tracing, which converts the trace events into StructLogs
+type traceState struct {
+	frames  []traceFrame
+	storage map[common.Address]map[common.Hash]common.Hash
+}
+
+func (runner *TxRunner) onTraceEvent(event *C.struct_trace_event) {
+	if runner.traceState == nil {
+		runner.traceState = &traceState{storage: make(map[common.Address]map[common.Hash]common.Hash)}
+	}
+	ts := runner.traceState
+	depth := int(event.depth) + 1
+	switch event.kind {
+	case C.TRACE_EXECUTION_START:
+		addr := toAddress(&event.destination)
+		storageAddr := addr
+		if len(ts.frames) != 0 && (event.call_kind == C.EVMC_DELEGATECALL || event.call_kind == C.EVMC_CALLCODE) {
+			storageAddr = ts.frames[len(ts.frames)-1].storageAddr
+		}
+		if len(ts.frames) != 0 { // the calling instruction has paid for the gas given to this frame
+			caller := &ts.frames[len(ts.frames)-1]
+			runner.flushPendingLog(caller, int64(event.caller_gas_left)-int64(event.gas_left), nil)
+		}
+		ts.frames = append(ts.frames, traceFrame{storageAddr: storageAddr, startGas: uint64(event.gas_left)})
+		runner.Tracer.CaptureEnter(depth, addr, uint64(event.gas_left))
+	case C.TRACE_INSTRUCTION:
+		frame := &ts.frames[len(ts.frames)-1]
+		stack := convertStack(event)
+		runner.flushPendingLog(frame, int64(event.gas_left), stack)
+		log := &StructLog{
+			Pc:    uint64(event.pc),
+			Op:    vm.OpCode(event.opcode),
+			Gas:   uint64(event.gas_left),
+			Depth: depth,
+		}
+		if !runner.TraceConfig.DisableStack {
+			log.Stack = stack
+		}
+		if event.memory != nil {
+			log.Memory = C.GoBytes(unsafe.Pointer(event.memory), C.int(event.memory_size))
+		}
+		if !runner.TraceConfig.DisableStorage && len(stack) >= 1 {
+			if log.Op == vm.SSTORE && len(stack) >= 2 {
+				slots := ts.slotsOf(frame.storageAddr)
+				slots[stack[len(stack)-1].Bytes32()] = stack[len(stack)-2].Bytes32()
+				log.Storage = copySlots(slots)
+			} else if log.Op == vm.SLOAD {
+				frame.pendingSload = true
+				frame.sloadKey = stack[len(stack)-1].Bytes32()
+			}
+		}
+		frame.pending = log
+	case C.TRACE_EXECUTION_END:
+		frame := &ts.frames[len(ts.frames)-1]
+		runner.flushPendingLog(frame, int64(event.gas_left), nil)
+		output := C.GoBytes(unsafe.Pointer(event.output), C.int(event.output_size))
+		runner.Tracer.CaptureExit(depth, int(event.status_code), output, frame.startGas-uint64(event.gas_left))
+		ts.frames = ts.frames[:len(ts.frames)-1]
+	}
+}
+
+// Report the pending instruction of 'frame', with the gas left after paying for it and the stack after executing it
+func (runner *TxRunner) flushPendingLog(frame *traceFrame, gasLeft int64, stack []uint256.Int) {
+	log := frame.pending
+	if log == nil {
+		return
+	}
+	if gasLeft < 0 {
+		gasLeft = 0
+	}
+	if log.Gas > uint64(gasLeft) {
+		log.GasCost = log.Gas - uint64(gasLeft)
+	}
+	if frame.pendingSload && len(stack) != 0 {
+		slots := runner.traceState.slotsOf(frame.storageAddr)
+		slots[frame.sloadKey] = stack[len(stack)-1].Bytes32()
+		log.Storage = copySlots(slots)
+	}
+	frame.pendingSload = false
+	frame.pending = nil
+	runner.Tracer.CaptureState(log)
+}
+
+func (ts *traceState) slotsOf(addr common.Address) map[common.Hash]common.Hash {
+	slots, ok := ts.storage[addr]
+	if !ok {
+		slots = make(map[common.Hash]common.Hash)
+		ts.storage[addr] = slots
+	}
+	return slots
+}
+
+func copySlots(slots map[common.Hash]common.Hash) map[common.Hash]common.Hash {
+	res := make(map[common.Hash]common.Hash, len(slots))
+	for k, v := range slots {
+		res[k] = v
+	}
+	return res
+}
+
+func convertStack(event *C.struct_trace_event) []uint256.Int {
+	size := int(event.stack_size)
+	if size == 0 {
+		return nil
+	}
+	bz := C.GoBytes(unsafe.Pointer(event.stack), C.int(size*32))
+	stack := make([]uint256.Int, size)
+	for i := range stack {
+		stack[i].SetBytes32(bz[i*32 : i*32+32])
+	}
+	return stack
+}
+
+//export on_trace_event
+func on_trace_event(handler C.int, event *C.struct_trace_event) {
+	getRunner(int(handler)).onTraceEvent(event)
+}
+
+// StructLogger is a Tracer which collects all the StructLogs, for debug_traceTransaction
+type StructLogger struct {
+	logs []*StructLog
+}
+
+func (l *StructLogger) CaptureEnter(depth int, address common.Address, gas uint64) {}
+
+func (l *StructLogger) CaptureState(log *StructLog) {
+	l.logs = append(l.logs, log)
+}
+
+func (l *StructLogger) CaptureExit(depth int, status int, output []byte, gasUsed uint64) {}
+
+func (l *StructLogger) StructLogs() []*StructLog {
+	return l.logs
+}
+
+// The JSON format of StructLog used by geth's debug_traceTransaction
+type StructLogRes struct {
+	Pc      uint64             `json:"pc"`
+	Op      string             `json:"op"`
+	Gas     uint64             `json:"gas"`
+	GasCost uint64             `json:"gasCost"`
+	Depth   int                `json:"depth"`
+	Stack   *[]string          `json:"stack,omitempty"`
+	Memory  *[]string          `json:"memory,omitempty"`
+	Storage *map[string]string `json:"storage,omitempty"`
+}
+
+func FormatLogs(logs []*StructLog) []StructLogRes {
+	formatted := make([]StructLogRes, len(logs))
+	for index, log := range logs {
+		formatted[index] = StructLogRes{
+			Pc:      log.Pc,
+			Op:      log.Op.String(),
+			Gas:     log.Gas,
+			GasCost: log.GasCost,
+			Depth:   log.Depth,
+		}
+		if log.Stack != nil {
+			stack := make([]string, len(log.Stack))
+			for i := range log.Stack {
+				stack[i] = log.Stack[i].Hex()
+			}
+			formatted[index].Stack = &stack
+		}
+		if log.Memory != nil {
+			memory := make([]string, 0, (len(log.Memory)+31)/32)
+			for i := 0; i+32 <= len(log.Memory); i += 32 {
+				memory = append(memory, fmt.Sprintf("%x", log.Memory[i:i+32]))
+			}
+			formatted[index].Memory = &memory
+		}
+		if log.Storage != nil {
+			storage := make(map[string]string, len(log.Storage))
+			for k, v := range log.Storage {
+				storage[fmt.Sprintf("%x", k)] = fmt.Sprintf("%x", v)
+			}
+			formatted[index].Storage = &storage
+		}
+	}
+	return formatted
+}
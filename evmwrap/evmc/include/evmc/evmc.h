@@ -508,6 +508,38 @@ typedef enum evmc_storage_status (*evmc_set_storage_fn)(struct evmc_host_context
                                                         const evmc_bytes32* key,
                                                         const evmc_bytes32* value);
 
+/**
+ * Get transient storage callback function.
+ *
+ * This callback function is used by a VM to query the given account transient storage (EIP-1153)
+ * entry.
+ *
+ * @param context  The Host execution context.
+ * @param address  The address of the account.
+ * @param key      The index of the account's transient storage entry.
+ * @return         The transient storage value at the given storage key or null bytes
+ *                 if it is not set in the current transaction.
+ */
+typedef evmc_bytes32 (*evmc_get_transient_storage_fn)(struct evmc_host_context* context,
+                                                      const evmc_address* address,
+                                                      const evmc_bytes32* key);
+
+/**
+ * Set transient storage callback function.
+ *
+ * This callback function is used by a VM to update the given account transient storage (EIP-1153)
+ * entry, which is discarded at the end of the transaction.
+ *
+ * @param context  The pointer to the Host execution context.
+ * @param address  The address of the account.
+ * @param key      The index of the transient storage entry.
+ * @param value    The value to be stored.
+ */
+typedef void (*evmc_set_transient_storage_fn)(struct evmc_host_context* context,
+                                              const evmc_address* address,
+                                              const evmc_bytes32* key,
+                                              const evmc_bytes32* value);
+
 /**
  * Get balance callback function.
  *
@@ -711,6 +743,12 @@ struct evmc_host_interface
 
     /** Access storage callback function. */
     evmc_access_storage_fn access_storage;
+
+    /** Get transient storage callback function. */
+    evmc_get_transient_storage_fn get_transient_storage;
+
+    /** Set transient storage callback function. */
+    evmc_set_transient_storage_fn set_transient_storage;
 };
 
 
@@ -839,8 +877,15 @@ enum evmc_revision
      */
     EVMC_SHANGHAI = 10,
 
+    /**
+     * The Cancun revision.
+     *
+     * Only the transient storage (EIP-1153) is supported.
+     */
+    EVMC_CANCUN = 11,
+
     /** The maximum EVM revision supported. */
-    EVMC_MAX_REVISION = EVMC_SHANGHAI,
+    EVMC_MAX_REVISION = EVMC_CANCUN,
 
     /**
      * The latest known EVM revision with finalized specification.
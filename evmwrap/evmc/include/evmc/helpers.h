@@ -287,6 +287,8 @@ static inline const char* evmc_revision_to_string(enum evmc_revision rev)
         return "London";
     case EVMC_SHANGHAI:
         return "Shanghai";
+    case EVMC_CANCUN:
+        return "Cancun";
     }
     return "<unknown>";
 }
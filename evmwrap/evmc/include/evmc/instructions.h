@@ -94,6 +94,9 @@ enum evmc_opcode
     OP_MSIZE = 0x59,
     OP_GAS = 0x5a,
     OP_JUMPDEST = 0x5b,
+    OP_TLOAD = 0x5c,
+    OP_TSTORE = 0x5d,
+    OP_PUSH0 = 0x5f,
 
     OP_PUSH1 = 0x60,
     OP_PUSH2 = 0x61,
@@ -58,6 +58,9 @@ struct MockedAccount
     /// The account storage map.
     std::unordered_map<bytes32, storage_value> storage;
 
+    /// The account transient storage map (EIP-1153).
+    std::unordered_map<bytes32, bytes32> transient_storage;
+
     /// Helper method for setting balance by numeric type.
     void set_balance(uint64_t x) noexcept
     {
@@ -375,5 +378,26 @@ public:
         value.access_status = EVMC_ACCESS_WARM;
         return access_status;
     }
+
+    /// Get the account's transient storage value at the given key (EVMC host method).
+    bytes32 get_transient_storage(const address& addr, const bytes32& key) const noexcept override
+    {
+        const auto account_iter = accounts.find(addr);
+        if (account_iter == accounts.end())
+            return {};
+
+        const auto storage_iter = account_iter->second.transient_storage.find(key);
+        if (storage_iter != account_iter->second.transient_storage.end())
+            return storage_iter->second;
+        return {};
+    }
+
+    /// Set the account's transient storage value (EVMC host method).
+    void set_transient_storage(const address& addr,
+                               const bytes32& key,
+                               const bytes32& value) noexcept override
+    {
+        accounts[addr].transient_storage[key] = value;
+    }
 };
 }  // namespace evmc
@@ -158,6 +158,7 @@ constexpr inline GasCostTable gas_costs = []() noexcept {
     table[EVMC_LONDON][OP_BASEFEE] = 2;
 
     table[EVMC_SHANGHAI] = table[EVMC_LONDON];
+    table[EVMC_CANCUN] = table[EVMC_SHANGHAI];
 
     return table;
 }();
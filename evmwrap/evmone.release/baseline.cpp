@@ -113,6 +113,14 @@ evmc_result execute(const VM& vm, ExecutionState& state, const CodeAnalysis& ana
             const auto offset = static_cast<uint32_t>(pc - code);
             if (offset < state.code.size())  // Skip STOP from code padding.
                 tracer->notify_instruction_start(offset, state);
+
+            // Only the traced executions run in Baseline, and they can be cancelled, too.
+            // The step budget counts the basic blocks of Advanced, so it is not applied here.
+            if (INTX_UNLIKELY(interrupt.abort_flag != nullptr && *interrupt.abort_flag != 0))
+            {
+                state.status = EVMC_INTERNAL_ERROR;
+                goto exit;
+            }
         }
 
         const auto op = *pc;
@@ -423,12 +431,28 @@ evmc_result execute(const VM& vm, ExecutionState& state, const CodeAnalysis& ana
             }
             break;
         }
+        case OP_TLOAD:
+            tload(state);
+            break;
+        case OP_TSTORE:
+        {
+            const auto status_code = tstore(state);
+            if (status_code != EVMC_SUCCESS)
+            {
+                state.status = status_code;
+                goto exit;
+            }
+            break;
+        }
         case OP_GAS:
             state.stack.push(state.gas_left);
             break;
         case OP_JUMPDEST:
             break;
 
+        case OP_PUSH0:
+            push0(state.stack);
+            break;
         case OP_PUSH1:
             pc = load_push<1>(state, pc + 1);
             continue;
@@ -136,4 +136,20 @@ struct ExecutionState
         output_size = 0;
     }
 };
+
+/// The interruption of the executions in the current thread, set by evmone_set_interrupt.
+/// The advanced interpreter checks it at the beginning of every basic block.
+struct Interrupt
+{
+    /// Null means the executions cannot be interrupted
+    volatile int32_t* abort_flag = nullptr;
+    /// How many more basic blocks can be executed, negative means no limit
+    int64_t steps_left = -1;
+};
+
+extern thread_local Interrupt interrupt;
+
+/// The maximum init code size of CREATE and CREATE2 since Shanghai (EIP-3860) in the current thread,
+/// set by evmone_set_max_initcode_size.
+extern thread_local size_t max_initcode_size;
 }  // namespace evmone
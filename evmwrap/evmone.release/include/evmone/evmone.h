@@ -14,6 +14,66 @@ extern "C" {
 
 EVMC_EXPORT struct evmc_vm* evmc_create_evmone(void) EVMC_NOEXCEPT;
 
+/** The value evmone writes to the abort flag when the step budget is exhausted. */
+enum { EVMONE_ABORT_STEP_BUDGET = 2 };
+
+/**
+ * Interrupt the executions in the current thread: the advanced interpreter exits with
+ * EVMC_INTERNAL_ERROR at the beginning of a basic block once *abort_flag is non-zero, or once
+ * 'step_budget' basic blocks have been executed. A null abort_flag disables the interruption and
+ * a negative step_budget means no limit.
+ */
+EVMC_EXPORT void evmone_set_interrupt(volatile int32_t* abort_flag, int64_t step_budget) EVMC_NOEXCEPT;
+
+/**
+ * Set the maximum init code size of CREATE and CREATE2 in the current thread, which is checked since
+ * the Shanghai revision (EIP-3860). It is twice the maximum code size by default.
+ */
+EVMC_EXPORT void evmone_set_max_initcode_size(size_t max_size) EVMC_NOEXCEPT;
+
+/** The kinds of evmone_trace_event. */
+enum
+{
+    EVMONE_TRACE_EXECUTION_START = 0,
+    EVMONE_TRACE_INSTRUCTION = 1,
+    EVMONE_TRACE_EXECUTION_END = 2
+};
+
+/** An event reported to the callback tracer. */
+struct evmone_trace_event
+{
+    int kind;
+    int32_t depth;
+    int64_t gas_left;
+
+    /* Only for EVMONE_TRACE_EXECUTION_START. */
+    enum evmc_call_kind call_kind;
+    evmc_address destination;
+    int64_t caller_gas_left; /**< The gas left of the calling frame, before the call is charged. */
+
+    /* Only for EVMONE_TRACE_INSTRUCTION. */
+    uint32_t pc;
+    uint8_t opcode;
+    const evmc_bytes32* stack; /**< Big-endian words from the bottom to the top. */
+    int stack_size;
+    const uint8_t* memory; /**< NULL if memory is not traced. */
+    size_t memory_size;
+
+    /* Only for EVMONE_TRACE_EXECUTION_END. */
+    enum evmc_status_code status_code;
+    const uint8_t* output;
+    size_t output_size;
+};
+
+typedef void (*evmone_trace_fn)(void* user_data, const struct evmone_trace_event* event);
+
+/**
+ * Report every execution and every instruction of the VM to 'fn'. Only the baseline interpreter
+ * reports instructions, so the VM is switched to it.
+ */
+EVMC_EXPORT void evmone_add_callback_tracer(
+    struct evmc_vm* vm, evmone_trace_fn fn, void* user_data, bool with_memory) EVMC_NOEXCEPT;
+
 #if __cplusplus
 }
 #endif
@@ -158,6 +158,11 @@ constexpr inline GasCostTable gas_costs = []() noexcept {
     table[EVMC_LONDON][OP_BASEFEE] = 2;
 
     table[EVMC_SHANGHAI] = table[EVMC_LONDON];
+    table[EVMC_SHANGHAI][OP_PUSH0] = 2;
+
+    table[EVMC_CANCUN] = table[EVMC_SHANGHAI];
+    table[EVMC_CANCUN][OP_TLOAD] = warm_storage_read_cost;
+    table[EVMC_CANCUN][OP_TSTORE] = warm_storage_read_cost;
 
     return table;
 }();
@@ -251,6 +256,9 @@ constexpr inline std::array<Traits, 256> traits = []() noexcept {
     table[OP_MSIZE] = {"MSIZE", 0, 1};
     table[OP_GAS] = {"GAS", 0, 1};
     table[OP_JUMPDEST] = {"JUMPDEST", 0, 0};
+    table[OP_TLOAD] = {"TLOAD", 1, 0};
+    table[OP_TSTORE] = {"TSTORE", 2, -2};
+    table[OP_PUSH0] = {"PUSH0", 0, 1};
 
     table[OP_PUSH1] = {"PUSH1", 0, 1};
     table[OP_PUSH2] = {"PUSH2", 0, 1};
@@ -5,6 +5,7 @@
 #include "instructions.hpp"
 #include "analysis.hpp"
 #include "instruction_traits.hpp"
+#include <evmone/evmone.h>
 
 namespace evmone
 {
@@ -173,6 +174,16 @@ const instruction* opx_beginblock(const instruction* instr, AdvancedExecutionSta
 {
     auto& block = instr->arg.block;
 
+    if (INTX_UNLIKELY(interrupt.abort_flag != nullptr))
+    {
+        if (interrupt.steps_left == 0)
+            *interrupt.abort_flag = EVMONE_ABORT_STEP_BUDGET;
+        else if (interrupt.steps_left > 0)
+            interrupt.steps_left--;
+        if (*interrupt.abort_flag != 0)
+            return state.exit(EVMC_INTERNAL_ERROR);
+    }
+
     if ((state.gas_left -= block.gas_cost) < 0)
         return state.exit(EVMC_OUT_OF_GAS);
 
@@ -251,6 +262,8 @@ constexpr std::array<instruction_exec_fn, 256> instruction_implementations = [](
     table[OP_MSTORE8] = op<mstore8>;
     table[OP_SLOAD] = op<sload>;
     table[OP_SSTORE] = op_sstore;
+    table[OP_TLOAD] = op<tload>;
+    table[OP_TSTORE] = op<tstore>;
     table[OP_JUMP] = op_jump;
     table[OP_JUMPI] = op_jumpi;
     table[OP_PC] = op_pc;
@@ -258,6 +271,7 @@ constexpr std::array<instruction_exec_fn, 256> instruction_implementations = [](
     table[OP_GAS] = op_gas;
     table[OPX_BEGINBLOCK] = opx_beginblock;
 
+    table[OP_PUSH0] = op<push0>;
     for (auto op = size_t{OP_PUSH1}; op <= OP_PUSH8; ++op)
         table[op] = op_push_small;
     for (auto op = size_t{OP_PUSH9}; op <= OP_PUSH32; ++op)
@@ -318,6 +332,8 @@ constexpr std::array<instruction_exec_fn, 256> instruction_implementations = [](
 }();
 }  // namespace
 
+thread_local Interrupt interrupt;
+
 EVMC_EXPORT const op_table& get_op_table(evmc_revision rev) noexcept
 {
     static constexpr auto op_tables = []() noexcept {
@@ -660,12 +660,38 @@ inline evmc_status_code sstore(ExecutionState& state) noexcept
     return EVMC_SUCCESS;
 }
 
+/// TLOAD instruction implementation (EIP-1153).
+inline void tload(ExecutionState& state) noexcept
+{
+    auto& x = state.stack.top();
+    const auto key = intx::be::store<evmc::bytes32>(x);
+    x = intx::be::load<uint256>(state.host.get_transient_storage(state.msg->destination, key));
+}
+
+/// TSTORE instruction implementation (EIP-1153).
+inline evmc_status_code tstore(ExecutionState& state) noexcept
+{
+    if (state.msg->flags & EVMC_STATIC)
+        return EVMC_STATIC_MODE_VIOLATION;
+
+    const auto key = intx::be::store<evmc::bytes32>(state.stack.pop());
+    const auto value = intx::be::store<evmc::bytes32>(state.stack.pop());
+    state.host.set_transient_storage(state.msg->destination, key, value);
+    return EVMC_SUCCESS;
+}
+
 
 inline void msize(ExecutionState& state) noexcept
 {
     state.stack.push(state.memory.size());
 }
 
+/// PUSH0 instruction implementation (EIP-3855).
+inline void push0(Stack& stack) noexcept
+{
+    stack.push(0);
+}
+
 /// DUP instruction implementation.
 /// @tparam N  The number as in the instruction definition, e.g. DUP3 is dup<3>.
 template <size_t N>
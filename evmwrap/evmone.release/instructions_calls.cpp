@@ -3,6 +3,7 @@
 // SPDX-License-Identifier: Apache-2.0
 
 #include "instructions.hpp"
+#include "limits.hpp"
 
 namespace evmone
 {
@@ -103,6 +104,8 @@ template evmc_status_code call<EVMC_DELEGATECALL>(ExecutionState& state) noexcep
 template evmc_status_code call<EVMC_CALLCODE>(ExecutionState& state) noexcept;
 
 
+thread_local size_t max_initcode_size = 2 * max_code_size;
+
 template <evmc_call_kind Kind>
 evmc_status_code create(ExecutionState& state) noexcept
 {
@@ -113,9 +116,19 @@ evmc_status_code create(ExecutionState& state) noexcept
     const auto init_code_offset = state.stack.pop();
     const auto init_code_size = state.stack.pop();
 
+    if (state.rev >= EVMC_SHANGHAI && init_code_size > max_initcode_size)
+        return EVMC_OUT_OF_GAS;
+
     if (!check_memory(state, init_code_offset, init_code_size))
         return EVMC_OUT_OF_GAS;
 
+    if (state.rev >= EVMC_SHANGHAI)
+    {
+        const auto init_code_cost = num_words(static_cast<size_t>(init_code_size)) * 2;
+        if ((state.gas_left -= init_code_cost) < 0)
+            return EVMC_OUT_OF_GAS;
+    }
+
     auto salt = uint256{};
     if constexpr (Kind == EVMC_CREATE2)
     {
@@ -154,8 +154,83 @@ public:
         m_out << std::dec;  // Set number formatting to dec, JSON does not support other forms.
     }
 };
+
+/// @see create_callback_tracer()
+class CallbackTracer : public Tracer
+{
+    evmone_trace_fn m_fn;
+    void* m_user_data;
+    bool m_with_memory;
+    std::stack<int32_t> m_depths;
+    std::vector<const ExecutionState*> m_states;  ///< The states of the running frames, if known.
+    std::vector<evmc_bytes32> m_stack;            ///< Reused by every instruction.
+
+    void on_execution_start(
+        evmc_revision /*rev*/, const evmc_message& msg, bytes_view /*code*/) noexcept override
+    {
+        evmone_trace_event event{};
+        event.kind = EVMONE_TRACE_EXECUTION_START;
+        event.depth = msg.depth;
+        event.gas_left = msg.gas;
+        event.call_kind = msg.kind;
+        event.destination = msg.destination;
+        if (!m_states.empty() && m_states.back() != nullptr)
+            event.caller_gas_left = m_states.back()->gas_left;
+        m_depths.push(msg.depth);
+        m_states.push_back(nullptr);
+        m_fn(m_user_data, &event);
+    }
+
+    void on_instruction_start(uint32_t pc, const ExecutionState& state) noexcept override
+    {
+        m_states.back() = &state;
+        const auto size = state.stack.size();
+        m_stack.resize(static_cast<size_t>(size));
+        for (int i = 0; i < size; ++i)  // state.stack[0] is the top item
+            intx::be::store(m_stack[static_cast<size_t>(size - 1 - i)].bytes, state.stack[i]);
+
+        evmone_trace_event event{};
+        event.kind = EVMONE_TRACE_INSTRUCTION;
+        event.depth = m_depths.top();
+        event.gas_left = state.gas_left;
+        event.pc = pc;
+        event.opcode = state.code[pc];
+        event.stack = m_stack.data();
+        event.stack_size = size;
+        if (m_with_memory)
+        {
+            event.memory = state.memory.data();
+            event.memory_size = state.memory.size();
+        }
+        m_fn(m_user_data, &event);
+    }
+
+    void on_execution_end(const evmc_result& result) noexcept override
+    {
+        evmone_trace_event event{};
+        event.kind = EVMONE_TRACE_EXECUTION_END;
+        event.depth = m_depths.top();
+        event.gas_left = result.gas_left;
+        event.status_code = result.status_code;
+        event.output = result.output_data;
+        event.output_size = result.output_size;
+        m_depths.pop();
+        m_states.pop_back();
+        m_fn(m_user_data, &event);
+    }
+
+public:
+    CallbackTracer(evmone_trace_fn fn, void* user_data, bool with_memory) noexcept
+      : m_fn{fn}, m_user_data{user_data}, m_with_memory{with_memory}
+    {}
+};
 }  // namespace
 
+std::unique_ptr<Tracer> create_callback_tracer(evmone_trace_fn fn, void* user_data, bool with_memory)
+{
+    return std::make_unique<CallbackTracer>(fn, user_data, with_memory);
+}
+
 std::unique_ptr<Tracer> create_histogram_tracer(std::ostream& out)
 {
     return std::make_unique<HistogramTracer>(out);
@@ -4,6 +4,7 @@
 #pragma once
 
 #include <evmc/instructions.h>
+#include <evmone/evmone.h>
 #include <memory>
 #include <ostream>
 #include <string_view>
@@ -61,4 +62,8 @@ EVMC_EXPORT std::unique_ptr<Tracer> create_histogram_tracer(std::ostream& out);
 
 EVMC_EXPORT std::unique_ptr<Tracer> create_instruction_tracer(std::ostream& out);
 
+/// Creates the tracer which reports the executions and the instructions to a C callback.
+EVMC_EXPORT std::unique_ptr<Tracer> create_callback_tracer(
+    evmone_trace_fn fn, void* user_data, bool with_memory);
+
 }  // namespace evmone
@@ -82,4 +82,22 @@ EVMC_EXPORT evmc_vm* evmc_create_evmone() noexcept
 {
     return new evmone::VM{};
 }
+
+EVMC_EXPORT void evmone_set_interrupt(volatile int32_t* abort_flag, int64_t step_budget) noexcept
+{
+    evmone::interrupt = {abort_flag, step_budget};
+}
+
+EVMC_EXPORT void evmone_set_max_initcode_size(size_t max_size) noexcept
+{
+    evmone::max_initcode_size = max_size;
+}
+
+EVMC_EXPORT void evmone_add_callback_tracer(
+    evmc_vm* c_vm, evmone_trace_fn fn, void* user_data, bool with_memory) noexcept
+{
+    c_vm->execute = evmone::baseline::execute;
+    static_cast<evmone::VM*>(c_vm)->add_tracer(
+        evmone::create_callback_tracer(fn, user_data, with_memory));
+}
 }
@@ -34,7 +34,8 @@ extern void get_value(int handler,
                       size_t* size);
 extern evmc_bytes32 get_block_hash(int handler, uint64_t num);
 extern void collect_result(int handler, struct all_changed* result, struct evmc_result* ret_value);
-extern void call_precompiled_contract (struct evmc_address* contract_addr,
+extern void call_precompiled_contract (int handler,
+                                       struct evmc_address* contract_addr,
                                        void* input_ptr,
                                        int input_size,
                                        uint64_t* gas_left,
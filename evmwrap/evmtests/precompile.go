@@ -26,7 +26,7 @@ import "C"
 //byte{9}): &blake2F{},
 
 //export call_precompiled_contract
-func call_precompiled_contract(contract_addr *evmc_address,
+func call_precompiled_contract(handler C.int /*not used*/, contract_addr *evmc_address,
 	input_ptr unsafe.Pointer,
 	input_size C.int,
 	gas_left *C.uint64_t,
@@ -1,6 +1,7 @@
 #pragma once
 
 #include "./../evmc/include/evmc/evmc.h"
+#include "config_gen.h"
 
 #ifdef __cplusplus
 extern "C" {
@@ -106,10 +107,6 @@ struct all_changed {
 	size_t internal_tx_return_num;
 };
 
-struct config {
-	bool after_xhedge_fork;
-	bool after_symbolsbch_fork;
-};
 
 // Go environment passes information about a block through this struct to C environment
 struct block_info {
@@ -119,6 +116,7 @@ struct block_info {
 	int64_t gas_limit;         /**< The block gas limit. */
 	struct evmc_bytes32 difficulty; /**< The block difficulty. */
 	struct evmc_bytes32 chain_id;   /**< The blockchain's ChainID. */
+	struct evmc_bytes32 base_fee;   /**< The block base fee per gas (EIP-1559), for the BASEFEE opcode. */
 	struct config cfg;
 };
 
@@ -156,7 +154,8 @@ typedef void (*bridge_get_value_fn)(int handler,
                                     size_t* size);
 typedef struct evmc_bytes32 (*bridge_get_block_hash_fn)(int handler, uint64_t num);
 typedef void (*bridge_collect_result_fn)(int handler, struct all_changed* result, struct evmc_result* ret_value);
-typedef void (*bridge_call_precompiled_contract_fn)(struct evmc_address* contract_addr,
+typedef void (*bridge_call_precompiled_contract_fn)(int handler,
+                                                    struct evmc_address* contract_addr,
                                                     void* input_ptr,
                                                     int input_size,
                                                     uint64_t *gas_left,
@@ -190,6 +189,63 @@ int64_t zero_depth_call(evmc_uint256be gas_price,
 		     bridge_collect_result_fn collect_result_fn,
 		     bridge_call_precompiled_contract_fn call_precompiled_contract_fn);
 
+// Make the following zero_depth_call in the current thread abortable: it stops once *abort_flag is set to
+// non-zero by the Go environment, or once 'step_budget' basic blocks have been executed, in which case
+// *abort_flag is set to ABORT_STEP_BUDGET. Call it with (NULL, -1) to disable the interruption again.
+enum {
+	ABORT_CANCELLED = 1,
+	ABORT_STEP_BUDGET = 2,
+};
+void set_interrupt(volatile int32_t* abort_flag, int64_t step_budget);
+
+// The kinds of trace_event
+enum {
+	TRACE_EXECUTION_START = 0,
+	TRACE_INSTRUCTION = 1,
+	TRACE_EXECUTION_END = 2,
+};
+
+// An event reported to the Go environment during tracing
+struct trace_event {
+	int kind;
+	int32_t depth;
+	int64_t gas_left;
+	// only for TRACE_EXECUTION_START
+	enum evmc_call_kind call_kind;
+	struct evmc_address destination; // the address of the code, i.e., the callee of DELEGATECALL and CALLCODE
+	int64_t caller_gas_left; // the gas left of the calling frame, which has not paid for the call yet
+	// only for TRACE_INSTRUCTION
+	uint32_t pc;
+	uint8_t opcode;
+	const struct evmc_bytes32* stack; // big-endian words from the bottom to the top
+	int stack_size;
+	const uint8_t* memory; // NULL if memory is not traced
+	size_t memory_size;
+	// only for TRACE_EXECUTION_END
+	enum evmc_status_code status_code;
+	const uint8_t* output;
+	size_t output_size;
+};
+
+typedef void (*bridge_trace_fn)(int handler, struct trace_event* event);
+
+// Trace the following zero_depth_call in the current thread: every call frame and every instruction is
+// reported to 'trace_fn'. The traced executions run in evmone's baseline interpreter without AOT, so they
+// are slower. Call it with (NULL, false) to disable the tracing again.
+void set_tracer(bridge_trace_fn trace_fn, bool with_memory);
+
+// An entry of an EIP-2930 access list: an address, or a storage key of it if has_key is true
+struct access_list_item {
+	evmc_address address;
+	bool has_key;
+	evmc_bytes32 key;
+};
+
+// Use the access list for the following zero_depth_call in the current thread: its items are charged as
+// intrinsic gas and are warm from the beginning. It only works with EVMC_BERLIN and later revisions. Call it
+// with (NULL, 0) to clear the access list again.
+void set_access_list(const struct access_list_item* items, size_t count);
+
 #ifdef __cplusplus
 }
 #endif
@@ -0,0 +1,15 @@
+// Code generated by cmd/forkcfg DO NOT EDIT.
+
+#pragma once
+
+#include <stdbool.h>
+#include <stdint.h>
+
+// The forks which are activated at the current block, and the parameters changed by them
+struct config {
+	bool after_xhedge_fork; // xhedge
+	bool after_symbolsbch_fork; // symbolSbch
+	bool after_shanghai_fork; // shanghai
+	bool after_cancun_fork; // cancun
+	uint32_t max_code_size; // see ForkSchedule.MaxCodeSize
+};
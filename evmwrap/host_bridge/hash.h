@@ -49,3 +49,24 @@ public:
 	}
 };
 
+
+// a storage slot of an account, used to track the warm slots of EIP-2929
+struct address_slot {
+	evmc_address addr;
+	evmc_bytes32 key;
+};
+
+class hashfn_address_slot {
+public:
+	size_t operator() (address_slot const& s) const {
+		return fasthash(&s.addr.bytes[0], sizeof(evmc_address)) ^
+			fasthash(&s.key.bytes[0], sizeof(evmc_bytes32));
+	}
+};
+class equalfn_address_slot {
+public:
+	bool operator() (address_slot const& s1, address_slot const& s2) const {
+		return memcmp(s1.key.bytes, s2.key.bytes, sizeof(evmc_bytes32)) == 0 &&
+			memcmp(s1.addr.bytes, s2.addr.bytes, sizeof(evmc_address)) == 0;
+	}
+};
@@ -113,6 +113,19 @@ enum evmc_access_status evmc_access_storage(struct evmc_host_context* context,
 	return context->access_storage(*address, *key);
 }
 
+evmc_bytes32 evmc_get_transient_storage(struct evmc_host_context* context,
+                                        const evmc_address* address,
+                                        const evmc_bytes32* key) {
+	return context->get_transient_storage(*address, *key);
+}
+
+void evmc_set_transient_storage(struct evmc_host_context* context,
+                                const evmc_address* address,
+                                const evmc_bytes32* key,
+                                const evmc_bytes32* value) {
+	context->set_transient_storage(*address, *key, *value);
+}
+
 evmc_host_interface HOST_IFC {
 	.account_exists = evmc_account_exists,
 	.get_storage = evmc_get_storage,
@@ -127,7 +140,9 @@ evmc_host_interface HOST_IFC {
 	.get_block_hash = evmc_get_block_hash,
 	.emit_log = evmc_emit_log,
 	.access_account = evmc_access_account,
-	.access_storage = evmc_access_storage
+	.access_storage = evmc_access_storage,
+	.get_transient_storage = evmc_get_transient_storage,
+	.set_transient_storage = evmc_set_transient_storage
 };
 
 evmc_bytes32 ZERO_BYTES32 = {};
@@ -270,7 +285,7 @@ size_t evmc_host_context::copy_code(const evmc_address& addr, size_t code_offset
 
 // for SELFDESTRUCT
 void evmc_host_context::selfdestruct(const evmc_address& addr, const evmc_address& beneficiary) {
-	if(!txctrl->is_selfdestructed(addr)) {
+	if(!txctrl->is_selfdestructed(addr) && revision < EVMC_LONDON) { // EIP-3529 removes this refund
 		txctrl->add_refund(SELFDESTRUCT_REFUND_GAS);
 	}
 	uint256 balance = txctrl->get_balance(addr); //make a copy
@@ -310,7 +325,59 @@ void evmc_host_context::load_code(const evmc_address& addr) {
 	this->codehash = entry.codehash;
 }
 
+// It is also checked when entering a new call frame, because AOT-compiled contracts ignore evmone's check
+static thread_local volatile int32_t* interrupt_flag = nullptr;
+
+void set_interrupt(volatile int32_t* abort_flag, int64_t step_budget) {
+	static_assert(int(ABORT_STEP_BUDGET) == int(EVMONE_ABORT_STEP_BUDGET));
+	interrupt_flag = abort_flag;
+	evmone_set_interrupt(abort_flag, step_budget);
+}
+
+static thread_local bridge_trace_fn trace_fn = nullptr;
+static thread_local bool trace_memory = false;
+// the addresses of the code run by the traced frames, from the outermost to the innermost
+static thread_local std::vector<evmc_address> trace_code_addrs;
+
+void set_tracer(bridge_trace_fn fn, bool with_memory) {
+	static_assert(int(TRACE_EXECUTION_START) == int(EVMONE_TRACE_EXECUTION_START));
+	static_assert(int(TRACE_INSTRUCTION) == int(EVMONE_TRACE_INSTRUCTION));
+	static_assert(int(TRACE_EXECUTION_END) == int(EVMONE_TRACE_EXECUTION_END));
+	trace_fn = fn;
+	trace_memory = with_memory;
+}
+
+static thread_local const access_list_item* access_list = nullptr;
+static thread_local size_t access_list_size = 0;
+
+void set_access_list(const access_list_item* items, size_t count) {
+	access_list = items;
+	access_list_size = count;
+}
+
+// forward the events of evmone's tracer to the Go environment
+struct bridge_tracer {
+	bridge_trace_fn fn;
+	int handler;
+
+	static void on_event(void* user_data, const evmone_trace_event* ev) noexcept {
+		auto tracer = static_cast<bridge_tracer*>(user_data);
+		trace_event event {.kind=ev->kind, .depth=ev->depth, .gas_left=ev->gas_left,
+			.call_kind=ev->call_kind, .destination=ev->destination, .caller_gas_left=ev->caller_gas_left,
+			.pc=ev->pc, .opcode=ev->opcode, .stack=ev->stack, .stack_size=ev->stack_size,
+			.memory=ev->memory, .memory_size=ev->memory_size,
+			.status_code=ev->status_code, .output=ev->output, .output_size=ev->output_size};
+		if(ev->kind == EVMONE_TRACE_EXECUTION_START && !trace_code_addrs.empty()) {
+			event.destination = trace_code_addrs.back(); // differs from the message for DELEGATECALL and CALLCODE
+		}
+		tracer->fn(tracer->handler, &event);
+	}
+};
+
 evmc_result evmc_host_context::call(const evmc_message& call_msg) {
+	if(interrupt_flag != nullptr && *interrupt_flag != 0) {
+		return evmc_result{.status_code=EVMC_INTERNAL_ERROR, .gas_left=0};
+	}
 	txctrl->gas_trace_append(call_msg.gas|MSB64);
 	txctrl->add_internal_tx_call(call_msg);
 	evmc_host_context ctx(txctrl, call_msg, this->smallbuf, this->revision);
@@ -431,7 +498,8 @@ evmc_result evmc_host_context::run_precompiled_contract(const evmc_address& addr
 	int ret_value, out_of_gas, osize;
 	uint64_t gas_left = msg.gas;
 
-	this->txctrl->call_precompiled_contract((struct evmc_address*)&addr/*drop const*/, (void*)msg.input_data,
+	this->txctrl->call_precompiled_contract(this->txctrl->get_handler(),
+			(struct evmc_address*)&addr/*drop const*/, (void*)msg.input_data,
 			msg.input_size, &gas_left, &ret_value, &out_of_gas, this->smallbuf, &osize);
 	if(out_of_gas != 0) {
 		return evmc_result{.status_code=EVMC_OUT_OF_GAS};
@@ -496,8 +564,14 @@ evmc_result evmc_host_context::run_vm(size_t snapshot, const evmc_address* code_
 	if(this->code->size() == 0) {
 		return evmc_result{.status_code=EVMC_SUCCESS, .gas_left=msg.gas}; // do nothing
 	}
-	evmc_result result = txctrl->execute(nullptr, &HOST_IFC, this, this->revision, &msg,
+	if(trace_fn != nullptr) {
+		trace_code_addrs.push_back(*code_addr);
+	}
+	evmc_result result = txctrl->execute(txctrl->get_vm(), &HOST_IFC, this, this->revision, &msg,
 			code_addr, this->code->data(), this->code->size());
+	if(trace_fn != nullptr) {
+		trace_code_addrs.pop_back();
+	}
 	if(result.status_code != EVMC_SUCCESS) {
 		txctrl->revert_to_snapshot(snapshot);
 	}
@@ -536,6 +610,14 @@ bool evmc_host_context::create_pre_check(const evmc_address& new_addr) {
 	return true;
 }
 
+// the max size of the deployed code, and the max size of init code is twice of it (EIP-3860)
+static uint64_t max_code_size(const config& cfg) {
+	if(cfg.after_shanghai_fork && cfg.max_code_size != 0) {
+		return cfg.max_code_size;
+	}
+	return MAX_CODE_SIZE;
+}
+
 evmc_result evmc_host_context::create_with_contract_addr(const evmc_address& addr) {
 	if(msg.depth != 0) {
 		txctrl->incr_nonce(msg.sender);
@@ -544,6 +626,9 @@ evmc_result evmc_host_context::create_with_contract_addr(const evmc_address& add
 		return evmc_result{.status_code = EVMC_FAILURE, .gas_left = 0};
 	}
 	msg.destination = addr;
+	if(revision >= EVMC_BERLIN) {
+		txctrl->access_account(addr); // EIP-2929: the created address is warm
+	}
 	bytes input_as_code(msg.input_data, msg.input_size);
 	this->code = &input_as_code;
 	this->codehash = ZERO_BYTES32;
@@ -564,7 +649,7 @@ evmc_result evmc_host_context::create_with_contract_addr(const evmc_address& add
 		return result;
 	}
 
-	bool max_code_size_exceed = result.output_size > MAX_CODE_SIZE;
+	bool max_code_size_exceed = result.output_size > max_code_size(txctrl->get_cfg());
 	if(result.status_code == EVMC_SUCCESS && !max_code_size_exceed) {
 		int64_t create_data_gas = result.output_size * CREATE_DATA_GAS;
 		if(result.output_size >= 1 && result.output_data[0] == 0xEF) {
@@ -621,6 +706,40 @@ int64_t intrinsic_gas(const uint8_t* input_data, size_t input_size, bool is_cont
 	return gas;
 }
 
+// the intrinsic gas of the access list set by set_access_list (EIP-2930)
+static int64_t access_list_gas() {
+	int64_t gas = 0;
+	for(size_t i = 0; i < access_list_size; i++) {
+		gas += access_list[i].has_key ? TX_ACCESS_LIST_STORAGE_KEY_GAS : TX_ACCESS_LIST_ADDRESS_GAS;
+	}
+	return gas;
+}
+
+// EIP-2929: the sender, the destination, the precompiled contracts and the access list are warm when
+// a transaction starts, and so is the created address, which is handled by create_with_contract_addr
+static void warm_up(tx_control& txctrl, const evmc_message& msg, const config cfg) {
+	txctrl.access_account(msg.sender);
+	if(msg.kind != EVMC_CREATE) {
+		txctrl.access_account(msg.destination);
+	}
+	static const int64_t precompiled_ids[] = {1, 2, 3, 4, 5, 6, 7, 8, 9,
+		STAKING_CONTRACT_ID, SEP206_CONTRACT_ID, SEP101_CONTRACT_ID, SEP109_CONTRACT_ID};
+	for(int64_t id : precompiled_ids) {
+		if(is_precompiled(id, cfg)) {
+			evmc_address addr {};
+			addr.bytes[18] = uint8_t(id >> 8);
+			addr.bytes[19] = uint8_t(id);
+			txctrl.access_account(addr);
+		}
+	}
+	for(size_t i = 0; i < access_list_size; i++) {
+		txctrl.access_account(access_list[i].address);
+		if(access_list[i].has_key) {
+			txctrl.access_storage(access_list[i].address, access_list[i].key);
+		}
+	}
+}
+
 //__inline__ uint64_t rdtsc() {
 //  uint64_t a, d;
 //  __asm__ volatile ("rdtsc" : "=a" (a), "=d" (d));
@@ -657,17 +776,27 @@ int64_t zero_depth_call(evmc_uint256be gas_price,
 		.bigbuf = &bigbuf[0],
 		.handler = handler
 	};
+	if(revision >= EVMC_CANCUN && !block->cfg.after_cancun_fork) { // EIP-1153 needs the cancun fork
+		revision = EVMC_SHANGHAI;
+	}
 	bool is_contract_creation = is_zero_address(*destination);
 	int64_t intrinsic = intrinsic_gas(input_data, input_size, is_contract_creation);
+	if(is_contract_creation && block->cfg.after_shanghai_fork) { // EIP-3860
+		intrinsic += (input_size + 31) / 32 * INITCODE_WORD_GAS;
+	}
+	int64_t list_gas = revision >= EVMC_BERLIN ? access_list_gas() : 0;
+	intrinsic += list_gas;
 	if(is_contract_creation && intrinsic > gas_limit) {
 		// thus we can create zero account (TransactionSendingToZero)
-		int64_t no_create_gas = intrinsic_gas(input_data, input_size, false);
+		int64_t no_create_gas = intrinsic_gas(input_data, input_size, false) + list_gas;
 		if (no_create_gas <= gas_limit) {
 			intrinsic = no_create_gas;
 			is_contract_creation = false;
 		}
 	}
-	if(intrinsic > gas_limit) {
+	bool initcode_too_large = is_contract_creation && block->cfg.after_shanghai_fork &&
+		input_size > 2 * max_code_size(block->cfg);
+	if(intrinsic > gas_limit || initcode_too_large) {
 		evmc_result result {.status_code=EVMC_OUT_OF_GAS, .gas_left=0};
 		collect_result_fn(handler, nullptr, &result);
 		return 0;
@@ -682,7 +811,8 @@ int64_t zero_depth_call(evmc_uint256be gas_price,
 		.block_timestamp = block->timestamp,
 		.block_gas_limit = block->gas_limit,
 		.block_difficulty = block->difficulty,
-		.chain_id = block->chain_id
+		.chain_id = block->chain_id,
+		.block_base_fee = block->base_fee
 	};
 	auto msg = evmc_message {
 		.kind = is_contract_creation? EVMC_CREATE : EVMC_CALL,
@@ -696,8 +826,17 @@ int64_t zero_depth_call(evmc_uint256be gas_price,
 		.value = *value
 	};
 	evmc_vm* vm = evmc_create_evmone();
-	tx_control txctrl(&r, tx_context, vm->execute, query_executor_fn, 
-			call_precompiled_contract_fn, need_gas_estimation, block->cfg);
+	bridge_tracer tracer {.fn=trace_fn, .handler=handler};
+	if(trace_fn != nullptr) {
+		evmone_add_callback_tracer(vm, bridge_tracer::on_event, &tracer, trace_memory);
+		query_executor_fn = nullptr; // AOT-compiled contracts cannot be traced
+	}
+	evmone_set_max_initcode_size(2 * max_code_size(block->cfg));
+	tx_control txctrl(&r, tx_context, vm, query_executor_fn, 
+			call_precompiled_contract_fn, need_gas_estimation, block->cfg, revision);
+	if(revision >= EVMC_BERLIN) {
+		warm_up(txctrl, msg, block->cfg);
+	}
 	small_buffer smallbuf;
 	evmc_host_context ctx(&txctrl, msg, &smallbuf, revision);
 	uint256 balance = ctx.get_balance_as_uint256(*sender);
@@ -4,7 +4,7 @@
 #include "tx_ctrl.h"
 
 const uint64_t MAX_UINT64 = ~uint64_t(0);
-const uint64_t MAX_CODE_SIZE = 24576; // 24k
+const uint64_t MAX_CODE_SIZE = 24576; // 24k, replaced by config.max_code_size after the shanghai fork
 const uint64_t MAX_KEY_SIZE = 256;
 const uint64_t MAX_VALUE_SIZE = 24576; // 24k
 
@@ -185,5 +185,11 @@ public:
 	enum evmc_access_status access_storage(const evmc_address& addr, const evmc_bytes32& key) {
 		return txctrl->access_storage(addr, key);
 	}
+	evmc_bytes32 get_transient_storage(const evmc_address& addr, const evmc_bytes32& key) {
+		return txctrl->get_transient_value(addr, key);
+	}
+	void set_transient_storage(const evmc_address& addr, const evmc_bytes32& key, const evmc_bytes32& value) {
+		txctrl->set_transient_value(addr, key, value);
+	}
 	void check_eip158();
 };
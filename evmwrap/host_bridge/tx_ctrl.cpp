@@ -395,6 +395,15 @@ void journal_entry::revert(cached_state* state) {
 	case LOG_QUEUE_ADD:
 		state->pop_log();
 		break;
+	case ACCOUNT_ACCESS:
+		state->_unaccess_account(account_access.addr);
+		break;
+	case SLOT_ACCESS:
+		state->_unaccess_slot(slot_access.addr, slot_access.key);
+		break;
+	case TRANSIENT_CHG:
+		state->_set_transient(transient_change.addr, transient_change.key, transient_change.prev_value);
+		break;
 	}
 }
 
@@ -487,6 +496,16 @@ evmc_storage_status tx_control::set_value(uint64_t sequence, const evmc_bytes32&
 	const bytes& new_value = cstate.set_value(sequence, key, raw_value, &e.prev_value);
 	journal.push_back(e);
 	const bytes& origin = cstate.get_origin_value(sequence, key);
+	uint64_t sload_gas = SLOAD_GAS;
+	uint64_t sstore_reset_gas = SSTORE_RESET_GAS;
+	if(revision >= EVMC_BERLIN) { // EIP-2929 changes the base costs, so the refunds change accordingly
+		sload_gas = WARM_STORAGE_READ_COST;
+		sstore_reset_gas = SSTORE_RESET_GAS - COLD_SLOAD_COST;
+	}
+	uint64_t clears_schedule = SSTORE_CLEARS_SCHEDULE;
+	if(revision >= EVMC_LONDON) { // EIP-3529 reduces the refund of clearing a slot
+		clears_schedule = SSTORE_CLEARS_SCHEDULE_EIP3529;
+	}
 	//If current value equals new value (this is a no-op), SLOAD_GAS is deducted.
 	if(e.prev_value == new_value) {
 		return EVMC_STORAGE_UNCHANGED;
@@ -502,7 +521,7 @@ evmc_storage_status tx_control::set_value(uint64_t sequence, const evmc_bytes32&
 		//Otherwise, SSTORE_RESET_GAS gas is deducted. If new value is 0, add SSTORE_CLEARS_SCHEDULE
 		//gas to refund counter.
 			if(new_value.size() == 0) {
-				add_refund(SSTORE_CLEARS_SCHEDULE);
+				add_refund(clears_schedule);
 				return EVMC_STORAGE_DELETED;
 			} else {
 				return EVMC_STORAGE_MODIFIED;
@@ -515,22 +534,22 @@ evmc_storage_status tx_control::set_value(uint64_t sequence, const evmc_bytes32&
 			//If current value is 0 (also means that new value is not 0), remove SSTORE_CLEARS_SCHEDULE
 			//gas from refund counter.
 			if(e.prev_value.size() == 0) {
-				sub_refund(SSTORE_CLEARS_SCHEDULE);
+				sub_refund(clears_schedule);
 			}
 			//If new value is 0 (also means that current value is not 0), add SSTORE_CLEARS_SCHEDULE
 			//gas to refund counter.
 			if(new_value.size() == 0) {
-				add_refund(SSTORE_CLEARS_SCHEDULE);
+				add_refund(clears_schedule);
 			}
 		}
 		//If original value equals new value (this storage slot is reset)
 		if(origin == new_value) {
 			//If original value is 0, add SSTORE_SET_GAS - SLOAD_GAS to refund counter.
 			if(origin.size() == 0) {
-				add_refund(SSTORE_SET_GAS - SLOAD_GAS);
+				add_refund(SSTORE_SET_GAS - sload_gas);
 			} else {
 			//Otherwise, add SSTORE_RESET_GAS - SLOAD_GAS gas to refund counter.
-				add_refund(SSTORE_RESET_GAS - SLOAD_GAS);
+				add_refund(sstore_reset_gas - sload_gas);
 			}
 		}
 		return EVMC_STORAGE_MODIFIED_AGAIN;
@@ -4,6 +4,7 @@
 #include <string>
 #include <vector>
 #include <unordered_map>
+#include <unordered_set>
 #include <iostream>
 #include <string.h>
 #include "bridge.h"
@@ -139,6 +140,9 @@ using account_map = std::unordered_map<evmc_address, account_entry, hashfn_evmc_
 using creation_counter_map = std::unordered_map<uint8_t, creation_counter_entry>;
 using bytecode_map = std::unordered_map<evmc_address, bytecode_entry, hashfn_evmc_address, equalfn_evmc_address>;
 using value_map = std::unordered_map<storage_key, bytes, hashfn_storage_key, equalfn_storage_key>;
+using address_set = std::unordered_set<evmc_address, hashfn_evmc_address, equalfn_evmc_address>;
+using address_slot_set = std::unordered_set<address_slot, hashfn_address_slot, equalfn_address_slot>;
+using transient_map = std::unordered_map<address_slot, evmc_bytes32, hashfn_address_slot, equalfn_address_slot>;
 
 // Read the world state from the underlying Go environment
 struct world_state_reader {
@@ -186,6 +190,11 @@ class cached_state {
 	bytecode_map bytecodes;
 	value_map values;
 	value_map origin_values;
+	// the accounts and slots which are warm according to EIP-2929
+	address_set accessed_accounts;
+	address_slot_set accessed_slots;
+	// the transient storage of EIP-1153, which only lives as long as the transaction
+	transient_map transient_values;
 	world_state_reader* world;
 	std::vector<evm_log> logs;
 	friend struct journal_entry;
@@ -205,6 +214,15 @@ protected:
 	void _set_value(uint64_t sequence, const evmc_bytes32& key, bytes* value);
 	void _undelete_bytecode(const evmc_address& addr, bool dirty);
 	void _unset_bytecode(const evmc_address& addr, bool dirty);
+	void _unaccess_account(const evmc_address& addr) {
+		accessed_accounts.erase(addr);
+	}
+	void _unaccess_slot(const evmc_address& addr, const evmc_bytes32& key) {
+		accessed_slots.erase(address_slot{.addr=addr, .key=key});
+	}
+	void _set_transient(const evmc_address& addr, const evmc_bytes32& key, const evmc_bytes32& value) {
+		set_transient(addr, key, value);
+	}
 public:
 	uint64_t refund;
 	cached_state(world_state_reader* r):
@@ -232,12 +250,25 @@ public:
 	void pop_log() {
 		logs.pop_back();
 	}
-	bool has_account(const evmc_address& addr) {
-		return accounts.find(addr) != accounts.end();
+	// mark an account or a slot as warm, return false if it was already warm
+	bool access_account(const evmc_address& addr) {
+		return accessed_accounts.insert(addr).second;
 	}
-	bool has_value(uint64_t sequence, const evmc_bytes32& key) {
-		storage_key k = skey(sequence, key);
-		return values.find(k) != values.end();
+	bool access_slot(const evmc_address& addr, const evmc_bytes32& key) {
+		return accessed_slots.insert(address_slot{.addr=addr, .key=key}).second;
+	}
+	evmc_bytes32 get_transient(const evmc_address& addr, const evmc_bytes32& key) {
+		auto iter = transient_values.find(address_slot{.addr=addr, .key=key});
+		if(iter == transient_values.end()) {
+			return evmc_bytes32{};
+		}
+		return iter->second;
+	}
+	// set a transient value and return the old one
+	evmc_bytes32 set_transient(const evmc_address& addr, const evmc_bytes32& key, const evmc_bytes32& value) {
+		evmc_bytes32 old_value = get_transient(addr, key);
+		transient_values[address_slot{.addr=addr, .key=key}] = value;
+		return old_value;
 	}
 	// Before the transaction exits, the Go environment should examine how this cached subset was modified.
 	// The modified entries in cache are marked as "dirty".
@@ -289,6 +320,9 @@ enum journal_type {
 	CREATION_COUNTER_INCR,
 	REFUND_CHG,
 	LOG_QUEUE_ADD,
+	ACCOUNT_ACCESS,
+	SLOT_ACCESS,
+	TRANSIENT_CHG,
 };
 
 // We use Tagged-Union for journal_entry, instead of interface pointers, because it's friendly 
@@ -343,6 +377,21 @@ struct journal_entry {
 		struct {
 			uint64_t old_refund;
 		} refund_change;
+
+		struct {
+			evmc_address addr;
+		} account_access;
+
+		struct {
+			evmc_address addr;
+			evmc_bytes32 key;
+		} slot_access;
+
+		struct {
+			evmc_address addr;
+			evmc_bytes32 key;
+			evmc_bytes32 prev_value;
+		} transient_change;
 	};
 	void revert(cached_state* state);
 };
@@ -357,18 +406,21 @@ class tx_control {
 	cached_state cstate;
 	world_state_reader* world;
 	evmc_tx_context tx_context;
+	evmc_vm* vm;
 	evmc_execute_fn execute_fn;
 	bridge_query_executor_fn query_executor_fn;
 	bool need_gas_estimation;
 	config cfg;
+	enum evmc_revision revision;
 public:
 	// this function provides precompile contracts' functionality from Go to C
 	bridge_call_precompiled_contract_fn call_precompiled_contract;
 
-	tx_control(world_state_reader* r, const evmc_tx_context& c, evmc_execute_fn f,
-		bridge_query_executor_fn qef, bridge_call_precompiled_contract_fn cpc, bool nge, const config cfg):
-		journal(), cstate(r), world(r), tx_context(c), execute_fn(f), query_executor_fn(qef),
-		need_gas_estimation(nge), cfg(cfg), call_precompiled_contract(cpc) {
+	tx_control(world_state_reader* r, const evmc_tx_context& c, evmc_vm* v,
+		bridge_query_executor_fn qef, bridge_call_precompiled_contract_fn cpc, bool nge, const config cfg,
+		enum evmc_revision rev):
+		journal(), cstate(r), world(r), tx_context(c), vm(v), execute_fn(v->execute), query_executor_fn(qef),
+		need_gas_estimation(nge), cfg(cfg), revision(rev), call_precompiled_contract(cpc) {
 		journal.reserve(100);
 		if(need_gas_estimation) {
 			gas_trace.reserve(100);
@@ -379,6 +431,16 @@ public:
 		return cfg;
 	}
 
+	// the VM whose 'execute' is used as the interpreter, evmone's baseline interpreter needs it
+	evmc_vm* get_vm() {
+		return vm;
+	}
+
+	// the handler to a TxRunner in Go environment
+	int get_handler() {
+		return world->handler;
+	}
+
 	int64_t get_block_number() {
 		return tx_context.block_number;
 	}
@@ -463,12 +525,38 @@ public:
 	const bytecode_entry& get_bytecode_entry(const evmc_address& addr) {
 		return cstate.get_bytecode_entry(addr);
 	}
-	enum evmc_access_status access_account(const evmc_address& address) {
-		return cstate.has_account(address) ? EVMC_ACCESS_WARM : EVMC_ACCESS_COLD;
+	// EIP-2929: the first access to an account or a slot is cold, and it turns warm until the
+	// accessing call frame reverts
+	enum evmc_access_status access_account(const evmc_address& addr) {
+		if(!cstate.access_account(addr)) {
+			return EVMC_ACCESS_WARM;
+		}
+		journal_entry e {.type=ACCOUNT_ACCESS};
+		e.account_access.addr = addr;
+		journal.push_back(e);
+		return EVMC_ACCESS_COLD;
 	}
 	enum evmc_access_status access_storage(const evmc_address& addr, const evmc_bytes32& key) {
-		const account_info& info = cstate.get_account(addr);
-		return cstate.has_value(info.sequence, key) ? EVMC_ACCESS_WARM : EVMC_ACCESS_COLD;
+		if(!cstate.access_slot(addr, key)) {
+			return EVMC_ACCESS_WARM;
+		}
+		journal_entry e {.type=SLOT_ACCESS};
+		e.slot_access.addr = addr;
+		e.slot_access.key = key;
+		journal.push_back(e);
+		return EVMC_ACCESS_COLD;
+	}
+	// EIP-1153: the transient storage is reverted with the failed call frames, and it is discarded
+	// with this tx_control at the end of zero_depth_call
+	evmc_bytes32 get_transient_value(const evmc_address& addr, const evmc_bytes32& key) {
+		return cstate.get_transient(addr, key);
+	}
+	void set_transient_value(const evmc_address& addr, const evmc_bytes32& key, const evmc_bytes32& value) {
+		journal_entry e {.type=TRANSIENT_CHG};
+		e.transient_change.addr = addr;
+		e.transient_change.key = key;
+		e.transient_change.prev_value = cstate.set_transient(addr, key, value);
+		journal.push_back(e);
 	}
 	evmc_storage_status set_value(const evmc_address& addr, const evmc_bytes32& key, bytes_info value);
 	evmc_storage_status set_value(uint64_t sequence, const evmc_bytes32& key, bytes_info value);
@@ -498,6 +586,7 @@ const uint64_t SLOAD_GAS = 800;
 const uint64_t SSTORE_SET_GAS = 20000;
 const uint64_t SSTORE_RESET_GAS = 5000;
 const uint64_t SSTORE_CLEARS_SCHEDULE = 15000;
+const uint64_t SSTORE_CLEARS_SCHEDULE_EIP3529 = 4800;
 const uint64_t SELFDESTRUCT_REFUND_GAS = 24000;
 
 const uint64_t CREATE_DATA_GAS = 200;
@@ -505,5 +594,11 @@ const uint64_t TX_GAS  = 21000; // Per transaction not creating a contract.
 const uint64_t TX_GAS_CONTRACT_CREATION = 53000; // Per transaction that creates a contract.
 const uint64_t TX_DATA_ZERO_GAS = 4; // Per byte of data attached to a transaction that equals zero.
 const uint64_t TX_DATA_NON_ZERO_GAS = 16; // Per byte of data attached to a transaction that is not equal to zero.
+const uint64_t TX_ACCESS_LIST_ADDRESS_GAS = 2400; // Per address in the access list (EIP-2930)
+const uint64_t TX_ACCESS_LIST_STORAGE_KEY_GAS = 1900; // Per storage key in the access list (EIP-2930)
+const uint64_t INITCODE_WORD_GAS = 2; // Per word of the init code of a contract creation (EIP-3860)
+
+const uint64_t WARM_STORAGE_READ_COST = 100; // EIP-2929
+const uint64_t COLD_SLOAD_COST = 2100; // EIP-2929
 
 const uint64_t MSB64 = (uint64_t(1)<<63);
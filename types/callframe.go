@@ -0,0 +1,186 @@
+package types
+
+import (
+	"encoding/json"
+	"math/big"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// The same as evmc_call_kind and EVMC_STATIC in evmc.h
+const (
+	evmcCall         = 0
+	evmcDelegateCall = 1
+	evmcCallCode     = 2
+	evmcCreate       = 3
+	evmcCreate2      = 4
+	evmcStatic       = 1
+)
+
+// The error strings used by geth's callTracer, indexed by evmc_status_code
+var callFrameErrors = map[int]string{
+	1:  "execution failed",
+	2:  "execution reverted",
+	3:  "out of gas",
+	4:  "invalid opcode",
+	5:  "invalid opcode",
+	6:  "stack overflow",
+	7:  "stack underflow",
+	8:  "invalid jump destination",
+	9:  "invalid memory access",
+	10: "max call depth exceeded",
+	11: "write protection",
+	12: "precompiled contract failed",
+	13: "contract validation failed",
+	14: "argument out of range",
+	17: "insufficient balance for transfer",
+	-1: "internal error",
+	-2: "rejected",
+	-3: "out of memory",
+}
+
+// One call frame of a transaction, like the result of geth's callTracer
+type CallFrame struct {
+	Type    string // CALL, STATICCALL, DELEGATECALL, CALLCODE, CREATE or CREATE2
+	From    gethcmn.Address
+	To      gethcmn.Address // the created address for CREATE and CREATE2, zero if the creation failed
+	Value   *big.Int        // nil for STATICCALL and DELEGATECALL
+	Gas     uint64
+	GasUsed uint64
+	Input   []byte
+	Output  []byte
+	Error   string // empty if the call succeeded
+	Calls   []*CallFrame
+}
+
+func callTypeOf(call *InternalTxCall) string {
+	switch call.Kind {
+	case evmcCall:
+		if call.Flags&evmcStatic != 0 {
+			return "STATICCALL"
+		}
+		return "CALL"
+	case evmcDelegateCall:
+		return "DELEGATECALL"
+	case evmcCallCode:
+		return "CALLCODE"
+	case evmcCreate:
+		return "CREATE"
+	case evmcCreate2:
+		return "CREATE2"
+	}
+	return "UNKNOWN"
+}
+
+// The error string of an evmc_status_code, or empty for EVMC_SUCCESS
+func CallFrameError(statusCode int) string {
+	if statusCode == 0 {
+		return ""
+	}
+	if s, ok := callFrameErrors[statusCode]; ok {
+		return s
+	}
+	return "unknown error"
+}
+
+// Build the tree of call frames from 'calls' and 'returns' recorded by the host bridge. 'calls' are
+// recorded when the frames start, so they are in pre-order, while 'returns' are recorded when the frames
+// finish, so they are in post-order. The root is the zero-depth call, whose Gas excludes the intrinsic gas.
+// If nothing is recorded (e.g. the TX is rejected), nil is returned.
+func BuildCallFrame(calls []InternalTxCall, returns []InternalTxReturn) (*CallFrame, error) {
+	if len(calls) == 0 && len(returns) == 0 {
+		return nil, nil
+	}
+	if len(calls) != len(returns) {
+		return nil, ErrBadInternalTxs
+	}
+	frames := make([]*CallFrame, len(calls))
+	stack := make([]int, 0, 8) // the indexes of the frames which have not returned
+	returnIdx := 0
+	// the frame on the stack top is finished, so its return is the next one
+	finish := func() {
+		idx := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		fillReturn(frames[idx], &calls[idx], &returns[returnIdx])
+		returnIdx++
+	}
+	for i := range calls {
+		call := &calls[i]
+		for len(stack) != 0 && calls[stack[len(stack)-1]].Depth >= call.Depth {
+			finish()
+		}
+		if len(stack) != int(call.Depth) || (i != 0 && len(stack) == 0) { // only the root has zero depth
+			return nil, ErrBadInternalTxs
+		}
+		frames[i] = newCallFrame(call)
+		if len(stack) != 0 {
+			parent := frames[stack[len(stack)-1]]
+			parent.Calls = append(parent.Calls, frames[i])
+		}
+		stack = append(stack, i)
+	}
+	for len(stack) != 0 {
+		finish()
+	}
+	return frames[0], nil
+}
+
+func newCallFrame(call *InternalTxCall) *CallFrame {
+	frame := &CallFrame{
+		Type:  callTypeOf(call),
+		From:  call.Sender,
+		To:    call.Destination,
+		Gas:   uint64(call.Gas),
+		Input: call.Input,
+	}
+	if frame.Type != "STATICCALL" && frame.Type != "DELEGATECALL" {
+		frame.Value = new(big.Int).SetBytes(call.Value[:])
+	}
+	return frame
+}
+
+func fillReturn(frame *CallFrame, call *InternalTxCall, ret *InternalTxReturn) {
+	if call.Gas > ret.GasLeft {
+		frame.GasUsed = uint64(call.Gas - ret.GasLeft)
+	}
+	frame.Output = ret.Output
+	frame.Error = CallFrameError(ret.StatusCode)
+	if call.Kind == evmcCreate || call.Kind == evmcCreate2 {
+		frame.To = ret.CreateAddress
+	}
+}
+
+// The call frame tree of this transaction, see BuildCallFrame
+func (tx *Transaction) CallFrame() (*CallFrame, error) {
+	return BuildCallFrame(tx.InternalTxCalls, tx.InternalTxReturns)
+}
+
+// The JSON format used by geth's callTracer
+type callFrameJSON struct {
+	Type    string          `json:"type"`
+	From    gethcmn.Address `json:"from"`
+	To      gethcmn.Address `json:"to"`
+	Value   *hexutil.Big    `json:"value,omitempty"`
+	Gas     hexutil.Uint64  `json:"gas"`
+	GasUsed hexutil.Uint64  `json:"gasUsed"`
+	Input   hexutil.Bytes   `json:"input"`
+	Output  hexutil.Bytes   `json:"output,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Calls   []*CallFrame    `json:"calls,omitempty"`
+}
+
+func (f *CallFrame) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&callFrameJSON{
+		Type:    f.Type,
+		From:    f.From,
+		To:      f.To,
+		Value:   (*hexutil.Big)(f.Value),
+		Gas:     hexutil.Uint64(f.Gas),
+		GasUsed: hexutil.Uint64(f.GasUsed),
+		Input:   f.Input,
+		Output:  f.Output,
+		Error:   f.Error,
+		Calls:   f.Calls,
+	})
+}
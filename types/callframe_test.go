@@ -0,0 +1,54 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildCallFrame(t *testing.T) {
+	a, b, c := [20]byte{0xa}, [20]byte{0xb}, [20]byte{0xc}
+	calls := []InternalTxCall{
+		{Kind: evmcCall, Depth: 0, Gas: 1000, Sender: a, Destination: b, Input: []byte{1}, Value: [32]byte{31: 5}},
+		{Kind: evmcCall, Flags: evmcStatic, Depth: 1, Gas: 500, Sender: b, Destination: c},
+		{Kind: evmcCreate, Depth: 1, Gas: 300, Sender: b},
+		{Kind: evmcDelegateCall, Depth: 2, Gas: 100, Sender: b, Destination: a},
+	}
+	// in post-order
+	returns := []InternalTxReturn{
+		{StatusCode: 0, GasLeft: 400, Output: []byte{2}},
+		{StatusCode: 2, GasLeft: 40},
+		{StatusCode: 3, GasLeft: 0, CreateAddress: [20]byte{0xd}},
+		{StatusCode: 0, GasLeft: 100},
+	}
+	root, err := BuildCallFrame(calls, returns)
+	require.NoError(t, err)
+	require.Equal(t, "CALL", root.Type)
+	require.Equal(t, uint64(900), root.GasUsed)
+	require.Equal(t, int64(5), root.Value.Int64())
+	require.Len(t, root.Calls, 2)
+	require.Equal(t, "STATICCALL", root.Calls[0].Type)
+	require.Nil(t, root.Calls[0].Value)
+	require.Equal(t, uint64(100), root.Calls[0].GasUsed)
+	require.Equal(t, []byte{2}, root.Calls[0].Output)
+	create := root.Calls[1]
+	require.Equal(t, "CREATE", create.Type)
+	require.Equal(t, "out of gas", create.Error)
+	require.Equal(t, [20]byte{0xd}, [20]byte(create.To))
+	require.Len(t, create.Calls, 1)
+	require.Equal(t, "DELEGATECALL", create.Calls[0].Type)
+	require.Equal(t, "execution reverted", create.Calls[0].Error)
+	require.Equal(t, uint64(60), create.Calls[0].GasUsed)
+
+	bz, err := json.Marshal(root.Calls[0])
+	require.NoError(t, err)
+	require.JSONEq(t, `{"type":"STATICCALL","from":"0x0b00000000000000000000000000000000000000",
+		"to":"0x0c00000000000000000000000000000000000000","gas":"0x1f4","gasUsed":"0x64",
+		"input":"0x","output":"0x02"}`, string(bz))
+
+	_, err = BuildCallFrame(calls, returns[:3])
+	require.Equal(t, ErrBadInternalTxs, err)
+	_, err = BuildCallFrame(append(calls, InternalTxCall{Depth: 0}), append(returns, InternalTxReturn{}))
+	require.Equal(t, ErrBadInternalTxs, err)
+}
@@ -2,6 +2,7 @@ package types
 
 import (
 	"bytes"
+	"encoding/binary"
 	"errors"
 	"math"
 
@@ -22,48 +23,35 @@ var (
 
 // update WithRbtCopy when fields change in Context
 type Context struct {
-	Rbt                 *rabbit.RabbitStore
-	Db                  modbtypes.DB
-	Height              int64
-	XHedgeForkBlock     int64
-	SymbolSbchForkBlock int64
-	StakingForkBlock    int64
-	ShaGateForkBlock    int64
-	Type                uint8
+	Rbt    *rabbit.RabbitStore
+	Db     modbtypes.DB
+	Height int64
+	Forks  *ForkSchedule // nil means no fork is activated
+	Type   uint8
 }
 
 func NewContext(rbt *rabbit.RabbitStore, db modbtypes.DB) *Context {
 	return &Context{
-		Rbt:                 rbt,
-		Db:                  db,
-		XHedgeForkBlock:     math.MaxInt64,
-		SymbolSbchForkBlock: math.MaxInt64,
-		StakingForkBlock:    math.MaxInt64,
-		ShaGateForkBlock:    math.MaxInt64,
+		Rbt: rbt,
+		Db:  db,
 	}
 }
 
 func (c *Context) WithRbt(rabbitStore *rabbit.RabbitStore) *Context {
 	return &Context{
-		Rbt:                 rabbitStore,
-		Db:                  c.Db,
-		XHedgeForkBlock:     c.XHedgeForkBlock,
-		SymbolSbchForkBlock: c.SymbolSbchForkBlock,
-		StakingForkBlock:    c.StakingForkBlock,
-		ShaGateForkBlock:    c.ShaGateForkBlock,
-		Height:              c.Height,
+		Rbt:    rabbitStore,
+		Db:     c.Db,
+		Forks:  c.Forks,
+		Height: c.Height,
 	}
 }
 
 func (c *Context) WithDb(db modbtypes.DB) *Context {
 	return &Context{
-		Rbt:                 c.Rbt,
-		Db:                  db,
-		XHedgeForkBlock:     c.XHedgeForkBlock,
-		SymbolSbchForkBlock: c.SymbolSbchForkBlock,
-		StakingForkBlock:    c.StakingForkBlock,
-		ShaGateForkBlock:    c.ShaGateForkBlock,
-		Height:              c.Height,
+		Rbt:    c.Rbt,
+		Db:     db,
+		Forks:  c.Forks,
+		Height: c.Height,
 	}
 }
 
@@ -78,40 +66,41 @@ func (c *Context) SetType(t uint8) {
 	c.Type = t
 }
 
-func (c *Context) SetXHedgeForkBlock(xHedgeForkBlock int64) {
-	c.XHedgeForkBlock = xHedgeForkBlock
-}
-
-func (c *Context) SetSymbolSbchBlock(symbolSbchForkBlock int64) {
-	c.SymbolSbchForkBlock = symbolSbchForkBlock
-}
-
-func (c *Context) SetStakingForkBlock(stakingForkBlock int64) {
-	c.StakingForkBlock = stakingForkBlock
-}
-
-func (c *Context) SetShaGateForkBlock(shaGateForkBlock int64) {
-	c.ShaGateForkBlock = shaGateForkBlock
+func (c *Context) SetForkSchedule(forks *ForkSchedule) {
+	c.Forks = forks
 }
 
 func (c *Context) SetCurrentHeight(height int64) {
 	c.Height = height
 }
 
+// Whether the fork named 'name' is activated at the current height
+func (c *Context) IsForkActive(name string) bool {
+	return c.Forks.IsActive(name, c.Height)
+}
+
 func (c *Context) IsXHedgeFork() bool {
-	return c.Height >= c.XHedgeForkBlock
+	return c.IsForkActive(XHedgeFork)
 }
 
 func (c *Context) IsSymbolSbchFork() bool {
-	return c.Height >= c.SymbolSbchForkBlock
+	return c.IsForkActive(SymbolSbchFork)
 }
 
 func (c *Context) IsStakingFork() bool {
-	return c.Height >= c.StakingForkBlock
+	return c.IsForkActive(StakingFork)
 }
 
 func (c *Context) IsShaGateFork() bool {
-	return c.Height >= c.ShaGateForkBlock
+	return c.IsForkActive(ShaGateFork)
+}
+
+// The max size of the deployed code at the current height, which is configurable after ShanghaiFork
+func (c *Context) MaxCodeSize() uint32 {
+	if !c.IsForkActive(ShanghaiFork) || c.Forks.MaxCodeSize == 0 {
+		return DefaultMaxCodeSize
+	}
+	return c.Forks.MaxCodeSize
 }
 
 //new empty rbt with same parent store as the old one
@@ -122,14 +111,11 @@ func (c *Context) WithRbtCopy() *Context {
 	parent := c.Rbt.GetBaseStore()
 	r := rabbit.NewRabbitStore(parent)
 	return &Context{
-		Rbt:                 &r,
-		Db:                  c.Db,
-		ShaGateForkBlock:    c.ShaGateForkBlock,
-		StakingForkBlock:    c.StakingForkBlock,
-		XHedgeForkBlock:     c.XHedgeForkBlock,
-		SymbolSbchForkBlock: c.SymbolSbchForkBlock,
-		Height:              c.Height,
-		Type:                c.Type,
+		Rbt:    &r,
+		Db:     c.Db,
+		Forks:  c.Forks,
+		Height: c.Height,
+		Type:   c.Type,
 	}
 }
 
@@ -245,6 +231,30 @@ func (c *Context) DeleteStorageAt(seq uint64, key string) {
 	c.Rbt.Delete(k)
 }
 
+// Return the height since which the TXs sent by 'addr' are rejected, if it is in the blocklist
+func (c *Context) GetBlockedSender(addr common.Address) (activationHeight int64, found bool) {
+	v := c.Rbt.Get(GetBlockedSenderKey(addr))
+	if len(v) != 8 {
+		return 0, false
+	}
+	return int64(binary.BigEndian.Uint64(v)), true
+}
+
+func (c *Context) SetBlockedSender(addr common.Address, activationHeight int64) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(activationHeight))
+	c.Rbt.Set(GetBlockedSenderKey(addr), buf[:])
+}
+
+func (c *Context) DeleteBlockedSender(addr common.Address) {
+	c.Rbt.Delete(GetBlockedSenderKey(addr))
+}
+
+func (c *Context) IsBlockedSender(addr common.Address) bool {
+	activationHeight, found := c.GetBlockedSender(addr)
+	return found && c.Height >= activationHeight
+}
+
 func (c *Context) GetCurrBlockBasicInfo() *Block {
 	blk := &Block{}
 	data := c.Rbt.Get([]byte{CURR_BLOCK_KEY})
@@ -13,4 +13,5 @@ var (
 	ErrTxNotFound          = errors.New("tx not found")
 	ErrNoFromAddr          = errors.New("missing from address")
 	ErrInvalidHeight       = errors.New("invalid height")
+	ErrBadInternalTxs      = errors.New("internal tx calls and returns do not match")
 )
@@ -0,0 +1,125 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// The names of the forks, used as the keys of ForkSchedule
+const (
+	XHedgeFork     = "xhedge"
+	SymbolSbchFork = "symbolSbch"
+	StakingFork    = "staking"
+	ShaGateFork    = "shaGate"
+	// The EVM switches from the Istanbul revision to Berlin, and the access lists of TXs are accepted
+	BerlinFork = "berlin"
+	// The EVM switches to the London revision, the dynamic-fee TXs of EIP-1559 are accepted and a part of
+	// their gas fee is burnt according to BlockInfo.BaseFee. It must not be earlier than BerlinFork.
+	LondonFork = "london"
+	// The EVM switches to the Shanghai revision with PUSH0 (EIP-3855) and the limit of init code (EIP-3860),
+	// and the max code size becomes ForkSchedule.MaxCodeSize. It must not be earlier than LondonFork.
+	ShanghaiFork = "shanghai"
+	// The EVM switches to the Cancun revision, which only brings the transient storage of EIP-1153 (TLOAD
+	// and TSTORE). It must not be earlier than ShanghaiFork.
+	CancunFork = "cancun"
+)
+
+// The max size of the deployed code, the same as MAX_CODE_SIZE in host_context.h
+const DefaultMaxCodeSize = 24576
+
+type ForkInfo struct {
+	Name string
+	// The bool field of 'struct config' in bridge.h which is true after the fork, or empty if the C
+	// environment does not care about this fork
+	CfgField string
+}
+
+// All the known forks. To add a fork, append it here and run "go generate" in ebp, which regenerates
+// 'struct config' in evmwrap/host_bridge/config_gen.h and the Go code filling it.
+var KnownForks = []ForkInfo{
+	{Name: XHedgeFork, CfgField: "after_xhedge_fork"},
+	{Name: SymbolSbchFork, CfgField: "after_symbolsbch_fork"},
+	{Name: StakingFork},
+	{Name: ShaGateFork},
+	{Name: BerlinFork},
+	{Name: LondonFork},
+	{Name: ShanghaiFork, CfgField: "after_shanghai_fork"},
+	{Name: CancunFork, CfgField: "after_cancun_fork"},
+}
+
+// The activation heights of the forks, which can be decoded from the "forks" table of a JSON or TOML
+// chain config, such as: [forks] xhedge = 4106000. A fork missing in Heights is never activated.
+// It is shared by pointer among the Contexts and must not be changed after it is loaded.
+type ForkSchedule struct {
+	Heights map[string]int64 `json:"forks" toml:"forks"`
+	// The max size of the deployed code after ShanghaiFork, and the max size of init code is twice of it.
+	// Zero means DefaultMaxCodeSize.
+	MaxCodeSize uint32 `json:"maxCodeSize" toml:"maxCodeSize"`
+}
+
+func NewForkSchedule() *ForkSchedule {
+	return &ForkSchedule{Heights: make(map[string]int64)}
+}
+
+// Decode a ForkSchedule from JSON like {"forks": {"xhedge": 4106000}}. The unknown forks are rejected.
+func ParseForkSchedule(bz []byte) (*ForkSchedule, error) {
+	s := NewForkSchedule()
+	if err := json.Unmarshal(bz, s); err != nil {
+		return nil, err
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Check that all the forks in the schedule are known, which catches misspelled names in configs
+func (s *ForkSchedule) Validate() error {
+	for name, height := range s.Heights {
+		known := false
+		for _, f := range KnownForks {
+			known = known || f.Name == name
+		}
+		if !known {
+			return fmt.Errorf("unknown fork: %s", name)
+		}
+		if height < 0 {
+			return fmt.Errorf("negative height of fork %s: %d", name, height)
+		}
+	}
+	if s.Height(LondonFork) < s.Height(BerlinFork) {
+		return fmt.Errorf("fork %s is earlier than %s", LondonFork, BerlinFork)
+	}
+	if s.Height(ShanghaiFork) < s.Height(LondonFork) {
+		return fmt.Errorf("fork %s is earlier than %s", ShanghaiFork, LondonFork)
+	}
+	if s.Height(CancunFork) < s.Height(ShanghaiFork) {
+		return fmt.Errorf("fork %s is earlier than %s", CancunFork, ShanghaiFork)
+	}
+	if s.MaxCodeSize != 0 && s.MaxCodeSize < DefaultMaxCodeSize {
+		return fmt.Errorf("maxCodeSize is smaller than %d: %d", DefaultMaxCodeSize, s.MaxCodeSize)
+	}
+	return nil
+}
+
+// Set the activation height of a fork, which is only used when building a schedule
+func (s *ForkSchedule) Set(name string, height int64) *ForkSchedule {
+	s.Heights[name] = height
+	return s
+}
+
+// The activation height of a fork, or math.MaxInt64 if it is not scheduled
+func (s *ForkSchedule) Height(name string) int64 {
+	if s == nil {
+		return math.MaxInt64
+	}
+	if h, ok := s.Heights[name]; ok {
+		return h
+	}
+	return math.MaxInt64
+}
+
+func (s *ForkSchedule) IsActive(name string, height int64) bool {
+	return height >= s.Height(name)
+}
@@ -0,0 +1,46 @@
+package types
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestForkSchedule(t *testing.T) {
+	forks, err := ParseForkSchedule([]byte(`{"forks": {"xhedge": 100, "staking": 200}}`))
+	require.NoError(t, err)
+	require.Equal(t, int64(100), forks.Height(XHedgeFork))
+	require.Equal(t, int64(math.MaxInt64), forks.Height(ShaGateFork))
+
+	ctx := NewContext(nil, nil)
+	require.False(t, ctx.IsXHedgeFork()) // no schedule
+	ctx.SetForkSchedule(forks)
+	ctx.SetCurrentHeight(99)
+	require.False(t, ctx.IsXHedgeFork())
+	ctx.SetCurrentHeight(100)
+	require.True(t, ctx.IsXHedgeFork())
+	require.False(t, ctx.IsStakingFork())
+	require.False(t, ctx.IsSymbolSbchFork())
+	// the schedule is shared by the derived contexts
+	require.True(t, ctx.WithDb(nil).IsForkActive(XHedgeFork))
+
+	_, err = ParseForkSchedule([]byte(`{"forks": {"xhedeg": 100}}`))
+	require.EqualError(t, err, "unknown fork: xhedeg")
+	_, err = ParseForkSchedule([]byte(`{"forks": {"berlin": 100, "london": 99}}`))
+	require.EqualError(t, err, "fork london is earlier than berlin")
+	_, err = ParseForkSchedule([]byte(`{"forks": {"shanghai": 100}}`))
+	require.EqualError(t, err, "fork shanghai is earlier than london")
+	_, err = ParseForkSchedule([]byte(`{"forks": {"berlin": 0, "london": 0, "shanghai": 100, "cancun": 99}}`))
+	require.EqualError(t, err, "fork cancun is earlier than shanghai")
+	_, err = ParseForkSchedule([]byte(`{"forks": {}, "maxCodeSize": 1024}`))
+	require.EqualError(t, err, "maxCodeSize is smaller than 24576: 1024")
+
+	forks, err = ParseForkSchedule([]byte(`{"forks": {"berlin": 0, "london": 0, "shanghai": 100}, "maxCodeSize": 49152}`))
+	require.NoError(t, err)
+	ctx.SetForkSchedule(forks)
+	ctx.SetCurrentHeight(99)
+	require.Equal(t, uint32(DefaultMaxCodeSize), ctx.MaxCodeSize())
+	ctx.SetCurrentHeight(100)
+	require.Equal(t, uint32(49152), ctx.MaxCodeSize())
+}
@@ -2,6 +2,7 @@ package types
 
 import (
 	"encoding/binary"
+	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
 	coretypes "github.com/ethereum/go-ethereum/core/types"
@@ -23,6 +24,7 @@ const ACCOUNT_KEY byte = 23
 const BYTECODE_KEY byte = 25
 const VALUE_KEY byte = 27
 const CURR_BLOCK_KEY byte = 29
+const BLOCKED_SENDER_KEY byte = 31
 
 var StandbyTxQueueKey [8]byte = [8]byte{255, 255, 255, 255, 255, 255, 255, 0}
 
@@ -63,6 +65,13 @@ func GetValueKey(seq uint64, key string) []byte {
 	return append(bz, []byte(key)...)
 }
 
+// The value is the 8-byte height since which the TXs sent by 'addr' are rejected
+func GetBlockedSenderKey(addr common.Address) []byte {
+	bz := make([]byte, 1, 1+len(addr))
+	bz[0] = BLOCKED_SENDER_KEY
+	return append(bz, addr[:]...)
+}
+
 func GetStandbyTxKey(num uint64) []byte {
 	var buf [8]byte
 	num += uint64(128+64) << 56 // raise it to the non-rabbit range
@@ -84,6 +93,7 @@ type BlockInfo struct {
 	GasLimit   int64
 	Difficulty [32]byte
 	ChainId    [32]byte
+	BaseFee    [32]byte // the base fee per gas of EIP-1559, which must be zero before the London fork
 }
 
 type BasicTx struct {
@@ -98,29 +108,57 @@ type BasicTx struct {
 
 type TxToRun struct {
 	BasicTx
-	HashID common.Hash
-	Height uint64
+	HashID     common.Hash
+	Height     uint64
+	AccessList coretypes.AccessList // the EIP-2930 access list, which is only allowed after the Berlin fork
 }
 
+// In the bytes of a TxToRun with an access list, the MSB of Height is set, Data is prefixed with its length
+// and the access list follows Nonce. The bytes of a TxToRun without an access list keep the old format.
+const accessListFlag = uint64(1) << 63
+
 func (tx TxToRun) ToBytes() []byte {
 	res := make([]byte, 0, 32+20+20+8+32+32+8+len(tx.Data)+8)
 	res = append(res, tx.HashID[:]...)
 	res = append(res, tx.From[:]...)
 	res = append(res, tx.To[:]...)
 	var buf [8]byte
-	binary.BigEndian.PutUint64(buf[:], tx.Height)
+	if len(tx.AccessList) == 0 {
+		binary.BigEndian.PutUint64(buf[:], tx.Height)
+	} else {
+		binary.BigEndian.PutUint64(buf[:], tx.Height|accessListFlag)
+	}
 	res = append(res, buf[:]...)
 	res = append(res, tx.Value[:]...)
 	res = append(res, tx.GasPrice[:]...)
 	binary.BigEndian.PutUint64(buf[:], tx.Gas)
 	res = append(res, buf[:]...)
+	if len(tx.AccessList) != 0 {
+		res = appendUint32(res, len(tx.Data))
+	}
 	res = append(res, tx.Data...)
 	var nonceBuf [8]byte
 	binary.BigEndian.PutUint64(nonceBuf[:], tx.Nonce)
 	res = append(res, nonceBuf[:]...)
+	if len(tx.AccessList) != 0 {
+		res = appendUint32(res, len(tx.AccessList))
+		for _, tuple := range tx.AccessList {
+			res = append(res, tuple.Address[:]...)
+			res = appendUint32(res, len(tuple.StorageKeys))
+			for _, key := range tuple.StorageKeys {
+				res = append(res, key[:]...)
+			}
+		}
+	}
 	return res
 }
 
+func appendUint32(bz []byte, n int) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], uint32(n))
+	return append(bz, buf[:]...)
+}
+
 func (tx *TxToRun) FromBytes(bz []byte) {
 	copy(tx.HashID[:], bz)
 	bz = bz[32:]
@@ -136,9 +174,34 @@ func (tx *TxToRun) FromBytes(bz []byte) {
 	bz = bz[32:]
 	tx.Gas = binary.BigEndian.Uint64(bz[:8])
 	bz = bz[8:]
-	tx.Data = append([]byte{}, bz[:len(bz)-8]...)
-	bz = bz[len(bz)-8:]
-	tx.Nonce = binary.BigEndian.Uint64(bz[:])
+	tx.AccessList = nil
+	if tx.Height&accessListFlag == 0 {
+		tx.Data = append([]byte{}, bz[:len(bz)-8]...)
+		bz = bz[len(bz)-8:]
+		tx.Nonce = binary.BigEndian.Uint64(bz[:])
+		return
+	}
+	tx.Height &^= accessListFlag
+	dataLen := binary.BigEndian.Uint32(bz[:4])
+	bz = bz[4:]
+	tx.Data = append([]byte{}, bz[:dataLen]...)
+	bz = bz[dataLen:]
+	tx.Nonce = binary.BigEndian.Uint64(bz[:8])
+	bz = bz[8:]
+	count := binary.BigEndian.Uint32(bz[:4])
+	bz = bz[4:]
+	tx.AccessList = make(coretypes.AccessList, count)
+	for i := range tx.AccessList {
+		copy(tx.AccessList[i].Address[:], bz)
+		bz = bz[20:]
+		keyCount := binary.BigEndian.Uint32(bz[:4])
+		bz = bz[4:]
+		tx.AccessList[i].StorageKeys = make([]common.Hash, keyCount)
+		for j := range tx.AccessList[i].StorageKeys {
+			copy(tx.AccessList[i].StorageKeys[j][:], bz)
+			bz = bz[32:]
+		}
+	}
 }
 
 func (tx *TxToRun) FromGethTx(gethTx *coretypes.Transaction, sender common.Address, height uint64) {
@@ -151,6 +214,20 @@ func (tx *TxToRun) FromGethTx(gethTx *coretypes.Transaction, sender common.Addre
 	tx.Gas = gethTx.Gas()
 	tx.Data = gethTx.Data()
 	tx.Nonce = gethTx.Nonce()
+	tx.AccessList = gethTx.AccessList()
 	copy(tx.Value[:], utils.BigIntToSlice32(gethTx.Value()))
 	copy(tx.GasPrice[:], utils.BigIntToSlice32(gethTx.GasPrice()))
 }
+
+// The gas price paid by 'gethTx' in a block whose base fee is 'baseFee'. For the dynamic-fee TXs of
+// EIP-1559, it is min(GasFeeCap, baseFee+GasTipCap). For the others, it is GasPrice.
+func EffectiveGasPrice(gethTx *coretypes.Transaction, baseFee *big.Int) *big.Int {
+	if gethTx.Type() != coretypes.DynamicFeeTxType {
+		return gethTx.GasPrice()
+	}
+	price := new(big.Int).Add(baseFee, gethTx.GasTipCap())
+	if price.Cmp(gethTx.GasFeeCap()) > 0 {
+		price.Set(gethTx.GasFeeCap())
+	}
+	return price
+}
@@ -0,0 +1,44 @@
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	coretypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxToRunBytes(t *testing.T) {
+	tx := TxToRun{
+		BasicTx: BasicTx{
+			From:  common.Address{0x01},
+			To:    common.Address{0x02},
+			Gas:   21000,
+			Data:  []byte{0xab, 0xcd},
+			Nonce: 7,
+		},
+		HashID: common.Hash{0x03},
+		Height: 100,
+	}
+	var tx2 TxToRun
+	tx2.FromBytes(tx.ToBytes())
+	require.Equal(t, tx, tx2)
+	require.Len(t, tx.ToBytes(), 32+20+20+8+32+32+8+2+8) // the format without access lists is unchanged
+
+	tx.AccessList = coretypes.AccessList{
+		{Address: common.Address{0x04}, StorageKeys: []common.Hash{{0x05}, {0x06}}},
+		{Address: common.Address{0x07}, StorageKeys: []common.Hash{}},
+	}
+	tx2.FromBytes(tx.ToBytes())
+	require.Equal(t, tx, tx2)
+	require.Equal(t, uint64(100), tx2.Height)
+}
+
+func TestEffectiveGasPrice(t *testing.T) {
+	legacy := coretypes.NewTransaction(0, common.Address{}, nil, 21000, big.NewInt(5), nil)
+	require.Equal(t, big.NewInt(5), EffectiveGasPrice(legacy, big.NewInt(3)))
+	dynamic := coretypes.NewTx(&coretypes.DynamicFeeTx{GasTipCap: big.NewInt(2), GasFeeCap: big.NewInt(10)})
+	require.Equal(t, big.NewInt(5), EffectiveGasPrice(dynamic, big.NewInt(3)))
+	require.Equal(t, big.NewInt(10), EffectiveGasPrice(dynamic, big.NewInt(9)))
+}
@@ -0,0 +1,179 @@
+package types
+
+import (
+	"bytes"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// The state of one account, like an entry of geth's prestateTracer output. A nil field is left out.
+type PrestateAccount struct {
+	Balance *hexutil.Big                  `json:"balance,omitempty"`
+	Nonce   *uint64                       `json:"nonce,omitempty"`
+	Code    hexutil.Bytes                 `json:"code,omitempty"`
+	Storage map[gethcmn.Hash]gethcmn.Hash `json:"storage,omitempty"`
+}
+
+type PrestateResult map[gethcmn.Address]*PrestateAccount
+
+// The output of geth's prestateTracer in diffMode
+type StateDiffResult struct {
+	Pre  PrestateResult `json:"pre"`
+	Post PrestateResult `json:"post"`
+}
+
+// Which account owns the storage of each sequence, according to the accounts in the lists
+func (rw *ReadWriteLists) SequenceToAddress() map[uint64]gethcmn.Address {
+	seqToAddr := make(map[uint64]gethcmn.Address)
+	for _, ops := range [][]AccountRWOp{rw.AccountRList, rw.AccountWList} {
+		for _, op := range ops {
+			if len(op.Account) != 0 { // deleted accounts have no bytes
+				seqToAddr[NewAccountInfo(op.Account).Sequence()] = op.Addr
+			}
+		}
+	}
+	return seqToAddr
+}
+
+func (res PrestateResult) accountOf(addr gethcmn.Address) *PrestateAccount {
+	acc, ok := res[addr]
+	if !ok {
+		acc = &PrestateAccount{}
+		res[addr] = acc
+	}
+	return acc
+}
+
+func (acc *PrestateAccount) setAccountInfo(bz []byte) {
+	info := NewAccountInfo(bz)
+	nonce := info.Nonce()
+	acc.Balance = (*hexutil.Big)(info.Balance().ToBig())
+	acc.Nonce = &nonce
+}
+
+func (acc *PrestateAccount) setStorage(key string, value []byte) {
+	if acc.Storage == nil {
+		acc.Storage = make(map[gethcmn.Hash]gethcmn.Hash)
+	}
+	acc.Storage[gethcmn.BytesToHash([]byte(key))] = gethcmn.BytesToHash(value)
+}
+
+// The state of the touched accounts before the TX, like geth's prestateTracer. The read lists only have
+// the values loaded from the world state, because the later accesses are served by the cache of the
+// host bridge, so they are the pre-images. The accounts that did not exist are left out. Note that the
+// sender's balance is read after the gas fee is deducted, and the nonce before it is increased.
+func (rw *ReadWriteLists) Prestate() PrestateResult {
+	res := make(PrestateResult)
+	for _, op := range rw.AccountRList {
+		res.accountOf(op.Addr).setAccountInfo(op.Account)
+	}
+	for _, op := range rw.BytecodeRList {
+		if len(op.Bytecode) != 0 {
+			res.accountOf(op.Addr).Code = NewBytecodeInfo(op.Bytecode).BytecodeSlice()
+		}
+	}
+	seqToAddr := rw.SequenceToAddress()
+	for _, op := range rw.StorageRList {
+		if addr, ok := seqToAddr[op.Seq]; ok {
+			res.accountOf(addr).setStorage(op.Key, op.Value)
+		}
+	}
+	return res
+}
+
+// The state of the touched accounts after the TX, i.e., Prestate with the write lists applied, and the
+// set of the accounts deleted by the TX, which are removed from the result
+func (rw *ReadWriteLists) poststate() (PrestateResult, map[gethcmn.Address]struct{}) {
+	res := rw.Prestate()
+	deleted := make(map[gethcmn.Address]struct{})
+	for _, op := range rw.AccountWList {
+		if len(op.Account) == 0 {
+			deleted[op.Addr] = struct{}{}
+			continue
+		}
+		delete(deleted, op.Addr)
+		res.accountOf(op.Addr).setAccountInfo(op.Account)
+	}
+	for _, op := range rw.BytecodeWList {
+		if len(op.Bytecode) == 0 {
+			res.accountOf(op.Addr).Code = nil
+		} else {
+			res.accountOf(op.Addr).Code = NewBytecodeInfo(op.Bytecode).BytecodeSlice()
+		}
+	}
+	seqToAddr := rw.SequenceToAddress()
+	for _, op := range rw.StorageWList {
+		if addr, ok := seqToAddr[op.Seq]; ok {
+			res.accountOf(addr).setStorage(op.Key, op.Value)
+		}
+	}
+	for addr := range deleted {
+		delete(res, addr)
+	}
+	return res, deleted
+}
+
+// The changes made by the TX, like geth's prestateTracer in diffMode. 'Pre' has the modified accounts
+// and 'Post' has their changed fields, while the storage slots are listed only if they are changed. The
+// created accounts are only in 'Post' and the deleted ones are only in 'Pre'.
+func (rw *ReadWriteLists) StateDiff() *StateDiffResult {
+	pre := rw.Prestate()
+	post, deleted := rw.poststate()
+	diff := &StateDiffResult{Pre: make(PrestateResult), Post: make(PrestateResult)}
+	for addr, postAcc := range post {
+		preAcc, existed := pre[addr]
+		if !existed {
+			diff.Post[addr] = postAcc
+			continue
+		}
+		changed := &PrestateAccount{}
+		if !bigEqual(preAcc.Balance, postAcc.Balance) {
+			changed.Balance = postAcc.Balance
+		}
+		if !uint64Equal(preAcc.Nonce, postAcc.Nonce) {
+			changed.Nonce = postAcc.Nonce
+		}
+		if !bytes.Equal(preAcc.Code, postAcc.Code) {
+			changed.Code = postAcc.Code
+		}
+		var preStorage map[gethcmn.Hash]gethcmn.Hash
+		for k, v := range postAcc.Storage {
+			if preAcc.Storage[k] == v {
+				continue
+			}
+			if changed.Storage == nil {
+				changed.Storage = make(map[gethcmn.Hash]gethcmn.Hash)
+				preStorage = make(map[gethcmn.Hash]gethcmn.Hash)
+			}
+			changed.Storage[k] = v
+			preStorage[k] = preAcc.Storage[k]
+		}
+		if changed.Balance == nil && changed.Nonce == nil && changed.Code == nil && changed.Storage == nil {
+			continue
+		}
+		diff.Post[addr] = changed
+		diff.Pre[addr] = &PrestateAccount{Balance: preAcc.Balance, Nonce: preAcc.Nonce, Code: preAcc.Code,
+			Storage: preStorage}
+	}
+	for addr := range deleted {
+		if preAcc, ok := pre[addr]; ok {
+			diff.Pre[addr] = preAcc
+		}
+	}
+	return diff
+}
+
+func bigEqual(a, b *hexutil.Big) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.ToInt().Cmp(b.ToInt()) == 0
+}
+
+func uint64Equal(a, b *uint64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
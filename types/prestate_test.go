@@ -0,0 +1,62 @@
+package types
+
+import (
+	"testing"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func accountBytes(balance, nonce, seq uint64) []byte {
+	acc := ZeroAccountInfo()
+	acc.UpdateBalance(uint256.NewInt(balance))
+	acc.UpdateNonce(nonce)
+	acc.UpdateSequence(seq)
+	return acc.Bytes()
+}
+
+func TestPrestateAndStateDiff(t *testing.T) {
+	sender, contract, created, destructed := gethcmn.Address{1}, gethcmn.Address{2}, gethcmn.Address{3}, gethcmn.Address{4}
+	code := append(make([]byte, 33), 0x60, 0x00)
+	key1, key2 := string(gethcmn.Hash{31: 1}.Bytes()), string(gethcmn.Hash{31: 2}.Bytes())
+	rw := &ReadWriteLists{
+		AccountRList: []AccountRWOp{
+			{Addr: sender, Account: accountBytes(100, 1, ^uint64(0))},
+			{Addr: contract, Account: accountBytes(0, 0, 0x102)},
+			{Addr: destructed, Account: accountBytes(7, 0, 0x204)},
+		},
+		AccountWList: []AccountRWOp{
+			{Addr: created, Account: accountBytes(0, 1, 0x303)},
+			{Addr: destructed},
+			{Addr: sender, Account: accountBytes(90, 2, ^uint64(0))},
+		},
+		BytecodeRList: []BytecodeRWOp{{Addr: contract, Bytecode: code}},
+		BytecodeWList: []BytecodeRWOp{{Addr: created, Bytecode: code}},
+		StorageRList:  []StorageRWOp{{Seq: 0x102, Key: key1, Value: []byte{5}}, {Seq: 0x102, Key: key2, Value: []byte{6}}},
+		StorageWList:  []StorageRWOp{{Seq: 0x102, Key: key1, Value: []byte{8}}, {Seq: 0x303, Key: key1, Value: []byte{9}}},
+	}
+
+	pre := rw.Prestate()
+	require.Len(t, pre, 3)
+	require.Equal(t, int64(100), pre[sender].Balance.ToInt().Int64())
+	require.Equal(t, uint64(1), *pre[sender].Nonce)
+	require.Equal(t, []byte{0x60, 0x00}, []byte(pre[contract].Code))
+	require.Equal(t, map[gethcmn.Hash]gethcmn.Hash{{31: 1}: {31: 5}, {31: 2}: {31: 6}}, pre[contract].Storage)
+
+	diff := rw.StateDiff()
+	require.Len(t, diff.Pre, 3)
+	require.Len(t, diff.Post, 3)
+	// only the changed fields and slots are listed
+	require.Equal(t, int64(90), diff.Post[sender].Balance.ToInt().Int64())
+	require.Equal(t, uint64(2), *diff.Post[sender].Nonce)
+	require.Nil(t, diff.Post[contract].Balance)
+	require.Equal(t, map[gethcmn.Hash]gethcmn.Hash{{31: 1}: {31: 8}}, diff.Post[contract].Storage)
+	require.Equal(t, map[gethcmn.Hash]gethcmn.Hash{{31: 1}: {31: 5}}, diff.Pre[contract].Storage)
+	// the created account is only in post, and the destructed one is only in pre
+	require.NotContains(t, diff.Pre, created)
+	require.Equal(t, []byte{0x60, 0x00}, []byte(diff.Post[created].Code))
+	require.Equal(t, map[gethcmn.Hash]gethcmn.Hash{{31: 1}: {31: 9}}, diff.Post[created].Storage)
+	require.NotContains(t, diff.Post, destructed)
+	require.Equal(t, int64(7), diff.Pre[destructed].Balance.ToInt().Int64())
+}
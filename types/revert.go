@@ -0,0 +1,121 @@
+package types
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	errorSelector = crypto.Keccak256([]byte("Error(string)"))[:4:4]
+	panicSelector = crypto.Keccak256([]byte("Panic(uint256)"))[:4:4]
+
+	stringArgs  = mustArguments("string")
+	uint256Args = mustArguments("uint256")
+)
+
+// The meanings of the panic codes of Solidity
+var panicReasons = map[uint64]string{
+	0x00: "generic panic",
+	0x01: "assert(false)",
+	0x11: "arithmetic underflow or overflow",
+	0x12: "division or modulo by zero",
+	0x21: "enum overflow",
+	0x22: "invalid encoded storage byte array accessed",
+	0x31: "out-of-bounds array access; popping on an empty array",
+	0x32: "out-of-bounds access of an array or bytesN",
+	0x41: "out of memory",
+	0x51: "uninitialized function",
+}
+
+func mustArguments(typeNames ...string) abi.Arguments {
+	args := make(abi.Arguments, len(typeNames))
+	for i, name := range typeNames {
+		typ, err := abi.NewType(name, "", nil)
+		if err != nil {
+			panic(err)
+		}
+		args[i] = abi.Argument{Type: typ}
+	}
+	return args
+}
+
+// A custom error of Solidity, such as "error InsufficientBalance(uint256 available, uint256 required)"
+type CustomError struct {
+	Name   string
+	Inputs abi.Arguments
+}
+
+// The custom errors known to the decoder, indexed by their selectors
+type ErrorRegistry map[[4]byte]CustomError
+
+// Add a custom error, whose selector is computed from its name and the types of its inputs
+func (r ErrorRegistry) Register(name string, inputs abi.Arguments) {
+	types := make([]string, len(inputs))
+	for i, input := range inputs {
+		types[i] = input.Type.String()
+	}
+	var selector [4]byte
+	copy(selector[:], crypto.Keccak256([]byte(name+"("+strings.Join(types, ",")+")")))
+	r[selector] = CustomError{Name: name, Inputs: inputs}
+}
+
+// Decode the output of a reverted call into a human-readable reason. Error(string) is decoded as the
+// string itself, Panic(uint256) as "panic: <meaning> (0x<code>)" and the custom errors found in
+// 'registry', which may be nil, as "Name(arg1, arg2, ...)". An empty string is returned if 'outData'
+// cannot be decoded.
+func DecodeRevertReason(outData []byte, registry ErrorRegistry) string {
+	if len(outData) < 4 {
+		return ""
+	}
+	selector, payload := outData[:4], outData[4:]
+	switch {
+	case bytes.Equal(selector, errorSelector):
+		values, err := stringArgs.Unpack(payload)
+		if err != nil {
+			return ""
+		}
+		return values[0].(string)
+	case bytes.Equal(selector, panicSelector):
+		values, err := uint256Args.Unpack(payload)
+		if err != nil {
+			return ""
+		}
+		code := values[0].(*big.Int)
+		reason := "unknown panic code"
+		if code.IsUint64() {
+			if r, ok := panicReasons[code.Uint64()]; ok {
+				reason = r
+			}
+		}
+		return fmt.Sprintf("panic: %s (0x%x)", reason, code)
+	}
+	var key [4]byte
+	copy(key[:], selector)
+	customErr, ok := registry[key]
+	if !ok {
+		return ""
+	}
+	values, err := customErr.Inputs.Unpack(payload)
+	if err != nil {
+		return ""
+	}
+	args := make([]string, len(values))
+	for i, v := range values {
+		args[i] = fmt.Sprintf("%v", v)
+	}
+	return customErr.Name + "(" + strings.Join(args, ", ") + ")"
+}
+
+// Decode the revert reason of this transaction with 'registry', which is needed for custom errors. The
+// RevertReason field is the result with a nil registry, filled when the transaction is committed.
+func (tx *Transaction) DecodeRevertReason(registry ErrorRegistry) string {
+	if tx.StatusStr != "revert" {
+		return ""
+	}
+	return DecodeRevertReason(tx.OutData, registry)
+}
@@ -0,0 +1,39 @@
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeRevertReason(t *testing.T) {
+	payload, err := stringArgs.Pack("not enough")
+	require.NoError(t, err)
+	require.Equal(t, "not enough", DecodeRevertReason(append(errorSelector, payload...), nil))
+
+	payload, err = uint256Args.Pack(big.NewInt(0x11))
+	require.NoError(t, err)
+	require.Equal(t, "panic: arithmetic underflow or overflow (0x11)", DecodeRevertReason(append(panicSelector, payload...), nil))
+	payload, err = uint256Args.Pack(big.NewInt(0x99))
+	require.NoError(t, err)
+	require.Equal(t, "panic: unknown panic code (0x99)", DecodeRevertReason(append(panicSelector, payload...), nil))
+
+	registry := make(ErrorRegistry)
+	inputs := mustArguments("uint256", "uint256")
+	registry.Register("InsufficientBalance", inputs)
+	payload, err = inputs.Pack(big.NewInt(1), big.NewInt(2))
+	require.NoError(t, err)
+	// cf479181 is the selector of InsufficientBalance(uint256,uint256)
+	outData := append([]byte{0xcf, 0x47, 0x91, 0x81}, payload...)
+	require.Equal(t, "", DecodeRevertReason(outData, nil))
+	require.Equal(t, "InsufficientBalance(1, 2)", DecodeRevertReason(outData, registry))
+
+	tx := &Transaction{StatusStr: "revert", OutData: outData}
+	require.Equal(t, "InsufficientBalance(1, 2)", tx.DecodeRevertReason(registry))
+	tx.StatusStr = "success"
+	require.Equal(t, "", tx.DecodeRevertReason(registry))
+
+	require.Equal(t, "", DecodeRevertReason([]byte{1, 2}, nil))
+	require.Equal(t, "", DecodeRevertReason(append(errorSelector, 1), nil))
+}
@@ -160,12 +160,68 @@ type Transaction struct {
 	Status            uint64    `msg:"status"`       //tx execute result: ReceiptStatusFailed or ReceiptStatusSuccessful
 	StatusStr         string    `msg:"statusstr"`    //tx execute result explained
 	OutData           []byte    `msg:"outdata"`      //the output data from the transaction
+	RevertReason      string    `msg:"revertreason"` //the decoded Error(string) or Panic(uint256) if the transaction reverted
 	//PostState  []byte  //look at Receipt.PostState
 
 	InternalTxCalls   []InternalTxCall   `msg:"itxcalls"`
 	InternalTxReturns []InternalTxReturn `msg:"itxreturns"`
 
 	RwLists *ReadWriteLists `msg:"rwlist"`
+
+	Rejection *TxRejection `msg:"rejection"` //why this transaction was rejected before execution, nil if it was executed
+}
+
+// The reasons for which a transaction is rejected before execution
+const (
+	REJECTED_INVALID_SIGNATURE    int = 1
+	REJECTED_GAS_PRICE_TOO_LOW    int = 2
+	REJECTED_GAS_LIMIT_TOO_HIGH   int = 3
+	REJECTED_ACCOUNT_NOT_EXIST    int = 4
+	REJECTED_INCORRECT_NONCE      int = 5
+	REJECTED_BLOCKED_SENDER       int = 6
+	REJECTED_INSUFFICIENT_BALANCE int = 7
+	REJECTED_TOO_OLD              int = 8
+	REJECTED_TX_TYPE_NOT_ALLOWED  int = 9
+	REJECTED_GAS_PRICE_TOO_HIGH   int = 10
+	REJECTED_TIP_ABOVE_FEE_CAP    int = 11
+)
+
+type TxRejection struct {
+	Code          int      `msg:"code"`          //one of the REJECTED_* constants
+	ExpectedNonce uint64   `msg:"expectednonce"` //the sender's nonce in world state, for REJECTED_INCORRECT_NONCE
+	ActualNonce   uint64   `msg:"actualnonce"`   //the transaction's nonce, for REJECTED_INCORRECT_NONCE
+	Shortfall     [32]byte `msg:"shortfall"`     //how much more balance is needed to pay the gas fee, for REJECTED_INSUFFICIENT_BALANCE
+	MinGasPrice   uint64   `msg:"mingasprice"`   //the gas-price floor, for REJECTED_GAS_PRICE_TOO_LOW
+	MaxGasLimit   uint64   `msg:"maxgaslimit"`   //the gas limit ceiling, for REJECTED_GAS_LIMIT_TOO_HIGH
+}
+
+// The explanation stored in Transaction.StatusStr
+func (r *TxRejection) String() string {
+	switch r.Code {
+	case REJECTED_INVALID_SIGNATURE:
+		return "invalid signature"
+	case REJECTED_GAS_PRICE_TOO_LOW:
+		return "invalid gas price"
+	case REJECTED_GAS_LIMIT_TOO_HIGH:
+		return "invalid gas limit"
+	case REJECTED_ACCOUNT_NOT_EXIST:
+		return "non-existent account"
+	case REJECTED_INCORRECT_NONCE:
+		return "incorrect nonce"
+	case REJECTED_BLOCKED_SENDER:
+		return "Blocked Account"
+	case REJECTED_INSUFFICIENT_BALANCE:
+		return "not enough balance to pay gasfee"
+	case REJECTED_TOO_OLD:
+		return "too old"
+	case REJECTED_TX_TYPE_NOT_ALLOWED:
+		return "transaction type not supported"
+	case REJECTED_GAS_PRICE_TOO_HIGH:
+		return "gas price out of range"
+	case REJECTED_TIP_ABOVE_FEE_CAP:
+		return "max priority fee per gas higher than max fee per gas"
+	}
+	return "unknown"
 }
 
 //TRANSACTION RECEIPT - A transaction receipt object, or null when no receipt was found:
@@ -3032,6 +3032,12 @@ func (z *Transaction) DecodeMsg(dc *msgp.Reader) (err error) {
 				err = msgp.WrapError(err, "OutData")
 				return
 			}
+		case "revertreason":
+			z.RevertReason, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "RevertReason")
+				return
+			}
 		case "itxcalls":
 			var zb0003 uint32
 			zb0003, err = dc.ReadArrayHeader()
@@ -3088,6 +3094,24 @@ func (z *Transaction) DecodeMsg(dc *msgp.Reader) (err error) {
 					return
 				}
 			}
+		case "rejection":
+			if dc.IsNil() {
+				err = dc.ReadNil()
+				if err != nil {
+					err = msgp.WrapError(err, "Rejection")
+					return
+				}
+				z.Rejection = nil
+			} else {
+				if z.Rejection == nil {
+					z.Rejection = new(TxRejection)
+				}
+				err = z.Rejection.DecodeMsg(dc)
+				if err != nil {
+					err = msgp.WrapError(err, "Rejection")
+					return
+				}
+			}
 		default:
 			err = dc.Skip()
 			if err != nil {
@@ -3101,9 +3125,9 @@ func (z *Transaction) DecodeMsg(dc *msgp.Reader) (err error) {
 
 // EncodeMsg implements msgp.Encodable
 func (z *Transaction) EncodeMsg(en *msgp.Writer) (err error) {
-	// map header, size 22
+	// map header, size 24
 	// write "hash"
-	err = en.Append(0xde, 0x0, 0x16, 0xa4, 0x68, 0x61, 0x73, 0x68)
+	err = en.Append(0xde, 0x0, 0x18, 0xa4, 0x68, 0x61, 0x73, 0x68)
 	if err != nil {
 		return
 	}
@@ -3299,6 +3323,16 @@ func (z *Transaction) EncodeMsg(en *msgp.Writer) (err error) {
 		err = msgp.WrapError(err, "OutData")
 		return
 	}
+	// write "revertreason"
+	err = en.Append(0xac, 0x72, 0x65, 0x76, 0x65, 0x72, 0x74, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e)
+	if err != nil {
+		return
+	}
+	err = en.WriteString(z.RevertReason)
+	if err != nil {
+		err = msgp.WrapError(err, "RevertReason")
+		return
+	}
 	// write "itxcalls"
 	err = en.Append(0xa8, 0x69, 0x74, 0x78, 0x63, 0x61, 0x6c, 0x6c, 0x73)
 	if err != nil {
@@ -3350,15 +3384,32 @@ func (z *Transaction) EncodeMsg(en *msgp.Writer) (err error) {
 			return
 		}
 	}
+	// write "rejection"
+	err = en.Append(0xa9, 0x72, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e)
+	if err != nil {
+		return
+	}
+	if z.Rejection == nil {
+		err = en.WriteNil()
+		if err != nil {
+			return
+		}
+	} else {
+		err = z.Rejection.EncodeMsg(en)
+		if err != nil {
+			err = msgp.WrapError(err, "Rejection")
+			return
+		}
+	}
 	return
 }
 
 // MarshalMsg implements msgp.Marshaler
 func (z *Transaction) MarshalMsg(b []byte) (o []byte, err error) {
 	o = msgp.Require(b, z.Msgsize())
-	// map header, size 22
+	// map header, size 24
 	// string "hash"
-	o = append(o, 0xde, 0x0, 0x16, 0xa4, 0x68, 0x61, 0x73, 0x68)
+	o = append(o, 0xde, 0x0, 0x18, 0xa4, 0x68, 0x61, 0x73, 0x68)
 	o = msgp.AppendBytes(o, (z.Hash)[:])
 	// string "index"
 	o = append(o, 0xa5, 0x69, 0x6e, 0x64, 0x65, 0x78)
@@ -3421,6 +3472,9 @@ func (z *Transaction) MarshalMsg(b []byte) (o []byte, err error) {
 	// string "outdata"
 	o = append(o, 0xa7, 0x6f, 0x75, 0x74, 0x64, 0x61, 0x74, 0x61)
 	o = msgp.AppendBytes(o, z.OutData)
+	// string "revertreason"
+	o = append(o, 0xac, 0x72, 0x65, 0x76, 0x65, 0x72, 0x74, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e)
+	o = msgp.AppendString(o, z.RevertReason)
 	// string "itxcalls"
 	o = append(o, 0xa8, 0x69, 0x74, 0x78, 0x63, 0x61, 0x6c, 0x6c, 0x73)
 	o = msgp.AppendArrayHeader(o, uint32(len(z.InternalTxCalls)))
@@ -3452,6 +3506,17 @@ func (z *Transaction) MarshalMsg(b []byte) (o []byte, err error) {
 			return
 		}
 	}
+	// string "rejection"
+	o = append(o, 0xa9, 0x72, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e)
+	if z.Rejection == nil {
+		o = msgp.AppendNil(o)
+	} else {
+		o, err = z.Rejection.MarshalMsg(o)
+		if err != nil {
+			err = msgp.WrapError(err, "Rejection")
+			return
+		}
+	}
 	return
 }
 
@@ -3600,6 +3665,12 @@ func (z *Transaction) UnmarshalMsg(bts []byte) (o []byte, err error) {
 				err = msgp.WrapError(err, "OutData")
 				return
 			}
+		case "revertreason":
+			z.RevertReason, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "RevertReason")
+				return
+			}
 		case "itxcalls":
 			var zb0003 uint32
 			zb0003, bts, err = msgp.ReadArrayHeaderBytes(bts)
@@ -3655,6 +3726,23 @@ func (z *Transaction) UnmarshalMsg(bts []byte) (o []byte, err error) {
 					return
 				}
 			}
+		case "rejection":
+			if msgp.IsNil(bts) {
+				bts, err = msgp.ReadNilBytes(bts)
+				if err != nil {
+					return
+				}
+				z.Rejection = nil
+			} else {
+				if z.Rejection == nil {
+					z.Rejection = new(TxRejection)
+				}
+				bts, err = z.Rejection.UnmarshalMsg(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Rejection")
+					return
+				}
+			}
 		default:
 			bts, err = msgp.Skip(bts)
 			if err != nil {
@@ -3673,7 +3761,7 @@ func (z *Transaction) Msgsize() (s int) {
 	for za0008 := range z.Logs {
 		s += z.Logs[za0008].Msgsize()
 	}
-	s += 6 + msgp.ArrayHeaderSize + (256 * (msgp.ByteSize)) + 7 + msgp.Uint64Size + 10 + msgp.StringPrefixSize + len(z.StatusStr) + 8 + msgp.BytesPrefixSize + len(z.OutData) + 9 + msgp.ArrayHeaderSize
+	s += 6 + msgp.ArrayHeaderSize + (256 * (msgp.ByteSize)) + 7 + msgp.Uint64Size + 10 + msgp.StringPrefixSize + len(z.StatusStr) + 8 + msgp.BytesPrefixSize + len(z.OutData) + 13 + msgp.StringPrefixSize + len(z.RevertReason) + 9 + msgp.ArrayHeaderSize
 	for za0010 := range z.InternalTxCalls {
 		s += z.InternalTxCalls[za0010].Msgsize()
 	}
@@ -3687,5 +3775,239 @@ func (z *Transaction) Msgsize() (s int) {
 	} else {
 		s += z.RwLists.Msgsize()
 	}
+	s += 10
+	if z.Rejection == nil {
+		s += msgp.NilSize
+	} else {
+		s += z.Rejection.Msgsize()
+	}
+	return
+}
+
+// DecodeMsg implements msgp.Decodable
+func (z *TxRejection) DecodeMsg(dc *msgp.Reader) (err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, err = dc.ReadMapHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, err = dc.ReadMapKeyPtr()
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "code":
+			z.Code, err = dc.ReadInt()
+			if err != nil {
+				err = msgp.WrapError(err, "Code")
+				return
+			}
+		case "expectednonce":
+			z.ExpectedNonce, err = dc.ReadUint64()
+			if err != nil {
+				err = msgp.WrapError(err, "ExpectedNonce")
+				return
+			}
+		case "actualnonce":
+			z.ActualNonce, err = dc.ReadUint64()
+			if err != nil {
+				err = msgp.WrapError(err, "ActualNonce")
+				return
+			}
+		case "shortfall":
+			err = dc.ReadExactBytes((z.Shortfall)[:])
+			if err != nil {
+				err = msgp.WrapError(err, "Shortfall")
+				return
+			}
+		case "mingasprice":
+			z.MinGasPrice, err = dc.ReadUint64()
+			if err != nil {
+				err = msgp.WrapError(err, "MinGasPrice")
+				return
+			}
+		case "maxgaslimit":
+			z.MaxGasLimit, err = dc.ReadUint64()
+			if err != nil {
+				err = msgp.WrapError(err, "MaxGasLimit")
+				return
+			}
+		default:
+			err = dc.Skip()
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z *TxRejection) EncodeMsg(en *msgp.Writer) (err error) {
+	// map header, size 6
+	// write "code"
+	err = en.Append(0x86, 0xa4, 0x63, 0x6f, 0x64, 0x65)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt(z.Code)
+	if err != nil {
+		err = msgp.WrapError(err, "Code")
+		return
+	}
+	// write "expectednonce"
+	err = en.Append(0xad, 0x65, 0x78, 0x70, 0x65, 0x63, 0x74, 0x65, 0x64, 0x6e, 0x6f, 0x6e, 0x63, 0x65)
+	if err != nil {
+		return
+	}
+	err = en.WriteUint64(z.ExpectedNonce)
+	if err != nil {
+		err = msgp.WrapError(err, "ExpectedNonce")
+		return
+	}
+	// write "actualnonce"
+	err = en.Append(0xab, 0x61, 0x63, 0x74, 0x75, 0x61, 0x6c, 0x6e, 0x6f, 0x6e, 0x63, 0x65)
+	if err != nil {
+		return
+	}
+	err = en.WriteUint64(z.ActualNonce)
+	if err != nil {
+		err = msgp.WrapError(err, "ActualNonce")
+		return
+	}
+	// write "shortfall"
+	err = en.Append(0xa9, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x66, 0x61, 0x6c, 0x6c)
+	if err != nil {
+		return
+	}
+	err = en.WriteBytes((z.Shortfall)[:])
+	if err != nil {
+		err = msgp.WrapError(err, "Shortfall")
+		return
+	}
+	// write "mingasprice"
+	err = en.Append(0xab, 0x6d, 0x69, 0x6e, 0x67, 0x61, 0x73, 0x70, 0x72, 0x69, 0x63, 0x65)
+	if err != nil {
+		return
+	}
+	err = en.WriteUint64(z.MinGasPrice)
+	if err != nil {
+		err = msgp.WrapError(err, "MinGasPrice")
+		return
+	}
+	// write "maxgaslimit"
+	err = en.Append(0xab, 0x6d, 0x61, 0x78, 0x67, 0x61, 0x73, 0x6c, 0x69, 0x6d, 0x69, 0x74)
+	if err != nil {
+		return
+	}
+	err = en.WriteUint64(z.MaxGasLimit)
+	if err != nil {
+		err = msgp.WrapError(err, "MaxGasLimit")
+		return
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z *TxRejection) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// map header, size 6
+	// string "code"
+	o = append(o, 0x86, 0xa4, 0x63, 0x6f, 0x64, 0x65)
+	o = msgp.AppendInt(o, z.Code)
+	// string "expectednonce"
+	o = append(o, 0xad, 0x65, 0x78, 0x70, 0x65, 0x63, 0x74, 0x65, 0x64, 0x6e, 0x6f, 0x6e, 0x63, 0x65)
+	o = msgp.AppendUint64(o, z.ExpectedNonce)
+	// string "actualnonce"
+	o = append(o, 0xab, 0x61, 0x63, 0x74, 0x75, 0x61, 0x6c, 0x6e, 0x6f, 0x6e, 0x63, 0x65)
+	o = msgp.AppendUint64(o, z.ActualNonce)
+	// string "shortfall"
+	o = append(o, 0xa9, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x66, 0x61, 0x6c, 0x6c)
+	o = msgp.AppendBytes(o, (z.Shortfall)[:])
+	// string "mingasprice"
+	o = append(o, 0xab, 0x6d, 0x69, 0x6e, 0x67, 0x61, 0x73, 0x70, 0x72, 0x69, 0x63, 0x65)
+	o = msgp.AppendUint64(o, z.MinGasPrice)
+	// string "maxgaslimit"
+	o = append(o, 0xab, 0x6d, 0x61, 0x78, 0x67, 0x61, 0x73, 0x6c, 0x69, 0x6d, 0x69, 0x74)
+	o = msgp.AppendUint64(o, z.MaxGasLimit)
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *TxRejection) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadMapHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, bts, err = msgp.ReadMapKeyZC(bts)
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "code":
+			z.Code, bts, err = msgp.ReadIntBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Code")
+				return
+			}
+		case "expectednonce":
+			z.ExpectedNonce, bts, err = msgp.ReadUint64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "ExpectedNonce")
+				return
+			}
+		case "actualnonce":
+			z.ActualNonce, bts, err = msgp.ReadUint64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "ActualNonce")
+				return
+			}
+		case "shortfall":
+			bts, err = msgp.ReadExactBytes(bts, (z.Shortfall)[:])
+			if err != nil {
+				err = msgp.WrapError(err, "Shortfall")
+				return
+			}
+		case "mingasprice":
+			z.MinGasPrice, bts, err = msgp.ReadUint64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "MinGasPrice")
+				return
+			}
+		case "maxgaslimit":
+			z.MaxGasLimit, bts, err = msgp.ReadUint64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "MaxGasLimit")
+				return
+			}
+		default:
+			bts, err = msgp.Skip(bts)
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z *TxRejection) Msgsize() (s int) {
+	s = 1 + 5 + msgp.IntSize + 14 + msgp.Uint64Size + 12 + msgp.Uint64Size + 10 + msgp.ArrayHeaderSize + (32 * (msgp.ByteSize)) + 12 + msgp.Uint64Size + 12 + msgp.Uint64Size
 	return
 }